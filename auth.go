@@ -0,0 +1,69 @@
+package requesto
+
+import (
+	"encoding/base64"
+	"maps"
+)
+
+// APIKeyLocation specifies where an API key is sent.
+type APIKeyLocation int
+
+const (
+	// APIKeyInHeader sends the API key as a request header.
+	APIKeyInHeader APIKeyLocation = iota
+	// APIKeyInQuery sends the API key as a URL query parameter.
+	APIKeyInQuery
+)
+
+// basicAuth returns the value of a Basic Authorization header.
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// SetBasicAuth sets a default Authorization header using HTTP Basic
+// authentication with the given credentials.
+func (c *Client) SetBasicAuth(username, password string) {
+	c.SetHeader("Authorization", basicAuth(username, password))
+}
+
+// SetBearerToken sets a default "Authorization: Bearer <token>" header.
+func (c *Client) SetBearerToken(token string) {
+	c.SetHeader("Authorization", "Bearer "+token)
+}
+
+// SetAPIKey sends an API key with every request, either as the header or as
+// the query parameter called name.
+func (c *Client) SetAPIKey(name, value string, in APIKeyLocation) {
+	if in == APIKeyInQuery {
+		params := make(map[string]string, len(c.Params)+1)
+		maps.Copy(params, c.Params)
+		params[name] = value
+		c.Params = params
+		return
+	}
+	c.SetHeader(name, value)
+}
+
+// SetBasicAuth sets the Authorization header using HTTP Basic authentication
+// with the given credentials.
+func (r *Request) SetBasicAuth(username, password string) *Request {
+	return r.SetHeader("Authorization", basicAuth(username, password))
+}
+
+// SetBearerToken sets the "Authorization: Bearer <token>" header.
+func (r *Request) SetBearerToken(token string) *Request {
+	return r.SetHeader("Authorization", "Bearer "+token)
+}
+
+// SetAPIKey sends an API key with the request, either as the header or as the
+// query parameter called name.
+func (r *Request) SetAPIKey(name, value string, in APIKeyLocation) *Request {
+	if r.err != nil {
+		return r
+	}
+	if in == APIKeyInQuery {
+		r.params = mergeParams(r.params, map[string]string{name: value})
+		return r
+	}
+	return r.SetHeader(name, value)
+}
@@ -0,0 +1,364 @@
+// Package cassette records HTTP interactions made through a requesto.Client
+// into JSON cassette files and replays them deterministically later.
+//
+// A Recorder is an http.RoundTripper and is installed with requesto.WithTransport:
+//
+//	rec, err := cassette.New("testdata/users.json", cassette.WithMode(cassette.ModeRecordMissing))
+//	if err != nil { ... }
+//	defer rec.Save()
+//	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(rec))
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrInteractionNotFound is returned when a request has no recorded
+// interaction and the recorder is not allowed to reach the network.
+var ErrInteractionNotFound = errors.New("cassette: no recorded interaction matches the request")
+
+// Mode controls whether a Recorder talks to the network, the cassette, or both.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette and fails on misses.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the network and records a fresh
+	// cassette, replacing the existing one on Save.
+	ModeRecord
+	// ModeRecordMissing replays known interactions and records new ones.
+	ModeRecordMissing
+	// ModePassthrough sends every request to the network without recording.
+	ModePassthrough
+)
+
+// redactedValue replaces the values of redacted headers in a cassette.
+const redactedValue = "REDACTED"
+
+// Interaction is a single recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the stored form of an outgoing request.
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RecordedResponse is the stored form of a received response.
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Status       string      `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// cassetteFile is the on-disk layout of a cassette.
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Option is a function that configures a Recorder.
+type Option func(*Recorder)
+
+// WithMode sets the recording mode. The default is ModeReplay.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithRealTransport sets the transport used to reach the network.
+// It defaults to http.DefaultTransport.
+func WithRealTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		if transport != nil {
+			r.real = transport
+		}
+	}
+}
+
+// WithRedactedHeaders replaces the list of headers whose values are redacted
+// before being written to the cassette. By default Authorization,
+// Proxy-Authorization, Cookie and Set-Cookie are redacted.
+func WithRedactedHeaders(names ...string) Option {
+	return func(r *Recorder) {
+		r.redact = names
+	}
+}
+
+// WithMatchOn sets the request fields used to find a recorded interaction.
+// The default is MatchMethod and MatchURL.
+func WithMatchOn(fields ...Field) Option {
+	return func(r *Recorder) {
+		r.matchOn = fields
+	}
+}
+
+// Recorder is an http.RoundTripper that records and replays interactions.
+// Responses fetched from the network in ModeRecord and ModeRecordMissing are
+// read fully into memory to be recorded, so their bodies are not streamed.
+type Recorder struct {
+	path    string
+	mode    Mode
+	real    http.RoundTripper
+	redact  []string
+	matchOn []Field
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+	dirty        bool
+}
+
+// New creates a Recorder backed by the cassette file at path. Existing
+// interactions are loaded unless the mode is ModeRecord. In ModeReplay the
+// cassette file must exist.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:    path,
+		mode:    ModeReplay,
+		real:    http.DefaultTransport,
+		redact:  []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		matchOn: []Field{MatchMethod, MatchURL},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeRecord {
+		// The cassette is rewritten even if nothing is recorded.
+		r.dirty = true
+		return r, nil
+	}
+	if r.mode == ModePassthrough {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && r.mode == ModeRecordMissing {
+			return r, nil
+		}
+		return nil, fmt.Errorf("cassette: cannot load %s: %w", path, err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cassette: cannot parse %s: %w", path, err)
+	}
+	r.interactions = file.Interactions
+	r.used = make([]bool, len(file.Interactions))
+	return r, nil
+}
+
+// Interactions returns the interactions currently held by the recorder.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file if anything new
+// was recorded. In ModeRecord the file is always replaced, even when no
+// request was made. Parent directories are created as needed.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(cassetteFile{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModePassthrough {
+		return r.real.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay || r.mode == ModeRecordMissing {
+		if interaction := r.find(req, body); interaction != nil {
+			return interaction.Response.toHTTP(req)
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+		}
+	}
+
+	resp, err := r.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.record(req, body, resp, respBody)
+	return resp, nil
+}
+
+// find returns the first unused interaction matching req, falling back to an
+// already used one so that repeated requests keep replaying.
+func (r *Recorder) find(req *http.Request, body []byte) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fallback := -1
+	for i, interaction := range r.interactions {
+		if !r.matches(req, body, &interaction.Request) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return r.interactions[fallback]
+	}
+	return nil
+}
+
+// matches reports whether req matches rec on every configured field.
+func (r *Recorder) matches(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	for _, field := range r.matchOn {
+		if !field(req, body, rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// record appends a new interaction built from the live exchange.
+func (r *Recorder) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) {
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redactHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     r.redactHeader(resp.Header),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BodyEncoding = encodeBody(reqBody)
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(respBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, interaction)
+	r.used = append(r.used, true)
+	r.dirty = true
+}
+
+// redactHeader returns a copy of h with sensitive values replaced.
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	clone := h.Clone()
+	for _, name := range r.redact {
+		if values := clone.Values(name); len(values) > 0 {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = redactedValue
+			}
+			clone[http.CanonicalHeaderKey(name)] = redacted
+		}
+	}
+	return clone
+}
+
+// toHTTP rebuilds an *http.Response from the recorded response.
+func (rr *RecordedResponse) toHTTP(req *http.Request) (*http.Response, error) {
+	body, err := decodeBody(rr.Body, rr.BodyEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: corrupt response body: %w", err)
+	}
+	header := rr.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        rr.Status,
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the request body and replaces it so it can still be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// encodeBody stores text bodies verbatim and binary bodies as base64.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// decodeBody reverses encodeBody.
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
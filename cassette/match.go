@@ -0,0 +1,47 @@
+package cassette
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// Field compares one aspect of an outgoing request with a recorded request.
+type Field func(req *http.Request, body []byte, rec *RecordedRequest) bool
+
+// MatchMethod matches on the HTTP method.
+func MatchMethod(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	return req.Method == rec.Method
+}
+
+// MatchURL matches on the full URL, including the query string.
+func MatchURL(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	return req.URL.String() == rec.URL
+}
+
+// MatchPath matches on the URL host and path, ignoring the query string.
+func MatchPath(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	u, err := url.Parse(rec.URL)
+	return err == nil && u.Host == req.URL.Host && u.Path == req.URL.Path
+}
+
+// MatchQuery matches on the query parameters regardless of their order.
+func MatchQuery(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	u, err := url.Parse(rec.URL)
+	return err == nil && u.Query().Encode() == req.URL.Query().Encode()
+}
+
+// MatchBody matches on the exact request body.
+func MatchBody(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	recorded, err := decodeBody(rec.Body, rec.BodyEncoding)
+	return err == nil && bytes.Equal(recorded, body)
+}
+
+// MatchHeader returns a Field that matches on the values of the named header.
+// Redacted headers never match.
+func MatchHeader(name string) Field {
+	return func(req *http.Request, body []byte, rec *RecordedRequest) bool {
+		return slices.Equal(req.Header.Values(name), rec.Header.Values(name))
+	}
+}
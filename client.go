@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"reflect"
 	"time"
 )
 
@@ -30,6 +31,16 @@ type Files map[string]File
 type Client struct {
 	httpClient  *http.Client
 	middlewares []Middleware
+	stream      bool
+	raise       bool
+	errorType   reflect.Type
+	tracer      Tracer
+	meter       Meter
+	trace       bool
+	hooked      bool // the proxy and dial hooks are installed on the transport
+	stats       *connStats
+	idleRead    time.Duration
+	err         error
 	CookieJar   http.CookieJar
 	BaseURL     string
 	Headers     http.Header
@@ -52,6 +63,8 @@ func NewClient(baseUrl string, opts ...ClientOption) *Client {
 	}
 	defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
 	defaultTransport.MaxIdleConnsPerHost = 100
+	// Connections are opened by the client's dialer, see installHooks.
+	defaultTransport.DialContext = nil
 
 	defaultHttpClient := &http.Client{
 		Transport: defaultTransport,
@@ -61,15 +74,33 @@ func NewClient(baseUrl string, opts ...ClientOption) *Client {
 
 	// Create and apply client configuration from options.
 	config := &clientConfig{
-		httpClient: defaultHttpClient,
+		httpClient:    defaultHttpClient,
+		ownsTransport: true,
+		dialer:        &dialer{},
 	}
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	// Requests can only override the proxy and socket when the transport is our own.
+	hooked := false
+	if t, ok := config.httpClient.Transport.(*http.Transport); ok && config.ownsTransport {
+		config.dialer.installHooks(t)
+		hooked = true
+	}
+
 	return &Client{
 		httpClient:  config.httpClient,
 		middlewares: make([]Middleware, 0),
+		stream:      config.stream,
+		raise:       config.raise,
+		tracer:      config.tracer,
+		meter:       config.meter,
+		trace:       config.trace,
+		hooked:      hooked,
+		stats:       config.dialer.stats,
+		idleRead:    config.idleReadTimeout,
+		err:         config.err,
 		CookieJar:   jar,
 		BaseURL:     baseUrl,
 		Headers:     make(http.Header),
@@ -97,6 +128,12 @@ func (c *Client) NewRequestWithContext(ctx context.Context) *Request {
 		formData:  make(map[string]string),
 		bodyBytes: []byte{},
 		files:     make(map[string]File),
+		stream:    c.stream,
+		raise:     c.raise,
+		errorType: c.errorType,
+		trace:     c.trace,
+		idleRead:  c.idleRead,
+		err:       c.err,
 	}
 }
 
@@ -113,6 +150,15 @@ func (c *Client) SetHeaders(h map[string]string) {
 	}
 }
 
+// SetHeader sets a single default header, replacing any existing values for
+// the key while keeping the other default headers.
+func (c *Client) SetHeader(key, value string) {
+	if c.Headers == nil {
+		c.Headers = make(http.Header)
+	}
+	c.Headers.Set(key, value)
+}
+
 // SetParams sets default query parameters that will be added to every request from this client.
 func (c *Client) SetParams(params map[string]string) {
 	c.Params = params
@@ -138,6 +184,14 @@ func (c *Client) SetFiles(files map[string]File) {
 	c.Files = files
 }
 
+// SetErrorResult registers the type used to decode the JSON body of 4xx and 5xx
+// responses for every request made by this client. v is only used for its type,
+// typically a pointer to a zero value such as &APIError{}. See
+// Request.SetErrorResult for details.
+func (c *Client) SetErrorResult(v any) {
+	c.errorType = errorResultType(v)
+}
+
 // SetCookiesFromMap adds cookies to the client's cookie jar from a map.
 // It requires the client to have a valid BaseURL and returns an error if it's missing or invalid.
 func (c *Client) SetCookiesFromMap(cookies map[string]string) error {
@@ -183,6 +237,37 @@ func (c *Client) Post() (*Response, error) {
 	return c.NewRequest().Post()
 }
 
+// Put creates and sends a new PUT request using the client's default settings.
+func (c *Client) Put() (*Response, error) {
+	return c.NewRequest().Put()
+}
+
+// Patch creates and sends a new PATCH request using the client's default settings.
+func (c *Client) Patch() (*Response, error) {
+	return c.NewRequest().Patch()
+}
+
+// Delete creates and sends a new DELETE request using the client's default settings.
+func (c *Client) Delete() (*Response, error) {
+	return c.NewRequest().Delete()
+}
+
+// Head creates and sends a new HEAD request using the client's default settings.
+func (c *Client) Head() (*Response, error) {
+	return c.NewRequest().Head()
+}
+
+// Options creates and sends a new OPTIONS request using the client's default settings.
+func (c *Client) Options() (*Response, error) {
+	return c.NewRequest().Options()
+}
+
+// Send creates and sends a new request with an arbitrary HTTP method using the
+// client's default settings.
+func (c *Client) Send(method string) (*Response, error) {
+	return c.NewRequest().Send(method)
+}
+
 // Use adds one or more middleware handlers to the client's middleware chain.
 func (c *Client) Use(middlewares ...Middleware) {
 	c.middlewares = append(c.middlewares, middlewares...)
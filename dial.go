@@ -0,0 +1,218 @@
+package requesto
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// socketContextKey is the context key under which the Unix socket of a
+// unix:// or http+unix:// request is stored.
+type socketContextKey struct{}
+
+// DialContextFunc is the signature of the function used to open connections,
+// as in net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dialer opens the connections of the client's transport. It routes requests
+// to Unix sockets, resolves host names as configured and delegates the
+// connection itself to dialContext.
+type dialer struct {
+	dialContext DialContextFunc
+	unixSocket  string
+	resolve     resolveConfig
+	keepAlive   time.Duration
+	// connectTimeout limits every dial, whatever the dial function.
+	connectTimeout time.Duration
+	defaultDial    bool
+	stats          *connStats
+}
+
+// DialContext implements http.Transport.DialContext.
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	socket := d.unixSocket
+	if s, ok := ctx.Value(socketContextKey{}).(string); ok {
+		socket = s
+	}
+	dialCtx := ctx
+	if d.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, d.connectTimeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	if socket != "" {
+		conn, err = d.dialContext(dialCtx, "unix", socket)
+	} else {
+		conn, err = d.dialTCP(dialCtx, network, address)
+	}
+	if err != nil {
+		if ctx.Err() == nil && isTimeout(err) {
+			return nil, &TimeoutError{Kind: TimeoutConnect, Duration: d.timeout(), Err: err}
+		}
+		return nil, err
+	}
+	if d.stats == nil {
+		return conn, nil
+	}
+	return d.stats.track(conn), nil
+}
+
+// timeout returns the connect timeout in effect, or 0 if it is not known.
+func (d *dialer) timeout() time.Duration {
+	if d.connectTimeout > 0 {
+		return d.connectTimeout
+	}
+	if d.defaultDial {
+		return 30 * time.Second
+	}
+	return 0
+}
+
+// installHooks makes t use the client's dialer, honor per-request proxies and
+// report connection statistics. A dial function already set on t is kept.
+func (d *dialer) installHooks(t *http.Transport) {
+	if d.dialContext == nil {
+		d.dialContext = t.DialContext
+	}
+	if d.dialContext == nil {
+		keepAlive := d.keepAlive
+		if keepAlive == 0 {
+			keepAlive = 30 * time.Second
+		}
+		d.dialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: keepAlive,
+		}).DialContext
+		d.defaultDial = true
+	}
+	d.stats = &connStats{}
+	t.DialContext = d.DialContext
+	t.Proxy = requestProxy(t.Proxy)
+}
+
+// WithDialer sets the net.Dialer used to open connections, for example to
+// bind a local address or tune keep-alives.
+func WithDialer(d *net.Dialer) ClientOption {
+	return func(c *clientConfig) {
+		if d != nil && c.transport("WithDialer") != nil {
+			c.dialer.dialContext = d.DialContext
+		}
+	}
+}
+
+// WithDialContext sets the function used to open connections. It receives the
+// network and address of the server, or of the proxy if one is used.
+func WithDialContext(fn DialContextFunc) ClientOption {
+	return func(c *clientConfig) {
+		if fn != nil && c.transport("WithDialContext") != nil {
+			c.dialer.dialContext = fn
+		}
+	}
+}
+
+// WithUnixSocket sends every request of the client over the Unix domain socket
+// at path, such as "/var/run/docker.sock". The URL host is still used for the
+// Host header, and proxies are disabled.
+func WithUnixSocket(path string) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithUnixSocket"); t != nil {
+			c.dialer.unixSocket = path
+			t.Proxy = nil
+		}
+	}
+}
+
+// SetUnixSocket sends the request over the Unix domain socket at path, as a
+// unix:// or http+unix:// URL does. The URL host is still used for the Host
+// header. Setting an absolute URL afterwards with SetURL or JoinPath replaces
+// the socket. It is not supported when the client uses a custom transport set
+// with WithTransport.
+func (r *Request) SetUnixSocket(path string) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.socket = path
+	return r
+}
+
+// UnixSocket returns the Unix domain socket the request is sent to, or an
+// empty string if it is not sent to one or the socket is set on the client
+// with WithUnixSocket.
+func (r *Request) UnixSocket() string {
+	if r.url == nil {
+		r.buildURL()
+	}
+	return r.socket
+}
+
+// parseURL parses rawURL, translating unix:// and http+unix:// URLs into an
+// http:// URL and the path of the socket to connect to. Two forms are
+// accepted:
+//
+//	unix:///var/run/docker.sock                  the whole path is the socket
+//	http+unix://%2Fvar%2Frun%2Fdocker.sock/info  the host is the escaped socket
+//
+// In the first form "/" is requested, so paths are added with JoinPath.
+// The socket is empty for other URLs.
+func parseURL(rawURL string) (*url.URL, string, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok || (!strings.EqualFold(scheme, "unix") && !strings.EqualFold(scheme, "http+unix")) {
+		u, err := url.Parse(rawURL)
+		return u, "", err
+	}
+
+	authority, requestURI := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority, requestURI = rest[:i], rest[i:]
+	}
+	u, err := url.Parse("http://localhost" + requestURI)
+	if err != nil {
+		return nil, "", err
+	}
+	if authority != "" {
+		socket, err := url.PathUnescape(authority)
+		if err != nil {
+			return nil, "", fmt.Errorf("requesto: invalid Unix socket in URL %q: %w", rawURL, err)
+		}
+		return u, socket, nil
+	}
+	socket := strings.TrimSuffix(u.Path, "/")
+	if socket == "" {
+		return nil, "", fmt.Errorf("requesto: no Unix socket in URL %q", rawURL)
+	}
+	u.Path, u.RawPath = "/", ""
+	return u, socket, nil
+}
+
+// socketTransport sends requests to a Unix socket through base. Only the
+// requests it hands to base carry a host identifying the socket, so that the
+// transport pools connections per socket; the client, its cookie jar and
+// errors keep seeing the URL of the request.
+type socketTransport struct {
+	base   http.RoundTripper
+	socket string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *socketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	poolReq := *req
+	poolURL := *req.URL
+	poolURL.Host = "unix-" + hex.EncodeToString([]byte(t.socket))
+	poolReq.URL = &poolURL
+	if poolReq.Host == "" {
+		poolReq.Host = req.URL.Host
+	}
+	resp, err := t.base.RoundTrip(&poolReq)
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, err
+}
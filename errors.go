@@ -1,9 +1,100 @@
 package requesto
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
 
 var (
 	ErrReadingBody         = errors.New("requesto: error reading response body")
 	ErrUnmarshallingJSON   = errors.New("requesto: error unmarshalling JSON response")
 	ErrUnmarshallingStruct = errors.New("requesto: error unmarshalling struct from JSON response")
+	ErrBodyNotReplayable   = errors.New("requesto: request body cannot be replayed")
 )
+
+// errBodyReleased is returned by the body accessors of a failed streaming
+// response whose body was closed by status checking.
+var errBodyReleased = fmt.Errorf("%w: the streaming body of a failed response was closed; see HTTPError.Body", ErrReadingBody)
+
+// maxErrorBodySnippet is the maximum number of body bytes kept in an HTTPError.
+const maxErrorBodySnippet = 512
+
+// HTTPError describes a response with a 4xx or 5xx status code.
+// It is returned by Response.RaiseForStatus and, when status checking is
+// enabled on the client or request, by the request itself. Use errors.As to
+// inspect it. In URL, the password and the values of the query parameters
+// are redacted, as they may hold credentials such as API keys.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// Body holds at most the first 512 bytes of the response body. When status
+	// checking fails a streaming response, only these bytes are read before the
+	// body is closed, and reading the body of Response fails with ErrReadingBody.
+	Body []byte
+	// Result holds the decoded error body when an error result type was
+	// registered with SetErrorResult, and nil otherwise.
+	Result any
+	// Response is the full response that caused the error.
+	Response *Response
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("requesto: %s %s: %s", e.Method, e.URL, e.Status)
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+	return msg
+}
+
+// redactURL renders u with its password and query parameter values replaced,
+// for use in errors and spans that may end up in logs.
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	query := u.Query()
+	for _, values := range query {
+		for i := range values {
+			values[i] = "REDACTED"
+		}
+	}
+	clone := *u
+	clone.RawQuery = query.Encode()
+	return clone.Redacted()
+}
+
+// newHTTPError builds an HTTPError from a response.
+func newHTTPError(r *Response) *HTTPError {
+	e := &HTTPError{
+		StatusCode: r.Resp.StatusCode,
+		Status:     r.Resp.Status,
+		Header:     r.Resp.Header,
+		Result:     r.errorResult,
+		Response:   r,
+	}
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if req := r.Resp.Request; req != nil {
+		e.Method = req.Method
+		if req.URL != nil {
+			e.URL = redactURL(req.URL)
+		}
+	}
+	if r.released {
+		e.Body = r.errorBody
+	} else if !r.IsStream() {
+		snippet := r.bodyBytes
+		if len(snippet) > maxErrorBodySnippet {
+			snippet = snippet[:maxErrorBodySnippet]
+		}
+		e.Body = snippet
+	}
+	return e
+}
@@ -2,27 +2,43 @@ package requesto
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 )
 
-// File represents a file to be uploaded, containing its name and content as an io.Reader.
+// File represents a file to be uploaded, containing its name and its content.
+//
+// The content is taken from Path if it is set, or from Content otherwise.
+// Files backed by a path or by an io.Seeker (such as *bytes.Reader or *os.File)
+// are rewound before every attempt, so they can be safely resent by retries.
+// Seekable content is read from the position it had when the body was first
+// built. If it implements io.Closer, as *os.File does, it is kept open across
+// retries and closed once the request, including every retry, has finished.
+// Any other io.Reader can only be sent once; resending it fails with
+// ErrBodyNotReplayable. Such a reader is closed after it has been read if it
+// implements io.Closer.
 type File struct {
 	Name    string
 	Content io.Reader
+	Path    string
 }
 
 // FileFromPath creates a File object from a given file path.
-// It opens the file and uses its base name as the file name.
+// It uses the file's base name as the file name. The file is opened each time
+// the request body is built and closed once it has been read.
 func FileFromPath(filePath string) (File, error) {
-	file, err := os.Open(filePath)
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return File{}, err
 	}
+	if info.IsDir() {
+		return File{}, fmt.Errorf("requesto: %s is a directory", filePath)
+	}
 	return File{
-		Name:    filepath.Base(filePath),
-		Content: file,
+		Name: filepath.Base(filePath),
+		Path: filePath,
 	}, nil
 }
 
@@ -34,3 +50,62 @@ func FileFromBytes(fileName string, data []byte) File {
 		Content: bytes.NewReader(data),
 	}
 }
+
+// open returns a reader positioned at the start of the file's content and a
+// function to release it. replay reports whether the content has already been
+// sent by a previous attempt of the same request. offset holds the position
+// seekable content started at: it is recorded on the first attempt and
+// restored on replays.
+func (f File) open(offset *int64, replay bool) (io.Reader, func(), error) {
+	noop := func() {}
+
+	if f.Path != "" {
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return nil, noop, err
+		}
+		return file, func() { file.Close() }, nil
+	}
+
+	if f.Content == nil {
+		return bytes.NewReader(nil), noop, nil
+	}
+
+	// Seekable content is rewound to where the caller left it and kept open
+	// so it can be read again; close releases it once the request is done.
+	if seeker, ok := f.Content.(io.Seeker); ok {
+		if !replay {
+			pos, err := seeker.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, noop, err
+			}
+			*offset = pos
+		} else if _, err := seeker.Seek(*offset, io.SeekStart); err != nil {
+			return nil, noop, err
+		}
+		return f.Content, noop, nil
+	}
+
+	if replay {
+		return nil, noop, fmt.Errorf("%w: file %q is not rewindable", ErrBodyNotReplayable, f.Name)
+	}
+	// If the content is an io.Closer, ensure it gets closed after the single read.
+	if closer, ok := f.Content.(io.Closer); ok {
+		return f.Content, func() { closer.Close() }, nil
+	}
+	return f.Content, noop, nil
+}
+
+// close closes seekable content that implements io.Closer. Other closable
+// content has already been closed by open after its single read.
+func (f File) close() {
+	if f.Path != "" || f.Content == nil {
+		return
+	}
+	if _, ok := f.Content.(io.Seeker); !ok {
+		return
+	}
+	if closer, ok := f.Content.(io.Closer); ok {
+		closer.Close()
+	}
+}
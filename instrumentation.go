@@ -0,0 +1,200 @@
+package requesto
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metric names recorded through a Meter. Durations are in seconds and sizes in bytes.
+const (
+	MetricRequestDuration  = "http.client.request.duration"
+	MetricRequestBodySize  = "http.client.request.body.size"
+	MetricResponseBodySize = "http.client.response.body.size"
+)
+
+// Attribute is a key-value pair attached to spans and measurements.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanContext identifies a span for W3C trace context propagation.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats the span context as a W3C traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return sc, false
+	}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != 16 {
+		return sc, false
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != 8 {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+// Span is a single traced operation. It mirrors the subset of the
+// OpenTelemetry span API used by requesto.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer creates spans. Implementations are expected to derive the parent
+// span from ctx and return a context carrying the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Meter records measurements such as MetricRequestDuration.
+type Meter interface {
+	Record(ctx context.Context, name string, value float64, attrs ...Attribute)
+}
+
+// traceRequest runs the middleware chain inside a span covering the whole
+// request, including every retry attempt.
+func (r *Request) traceRequest(chain Next) (*Response, error) {
+	parent := r.ctx
+	ctx, span := r.client.tracer.Start(parent, "HTTP "+r.method,
+		Attribute{Key: "http.request.method", Value: r.method},
+	)
+	r.ctx = ctx
+	defer func() { r.ctx = parent }()
+
+	resp, err := chain(r)
+
+	if u := r.URL(); u != nil {
+		span.SetAttributes(Attribute{Key: "url.full", Value: redactURL(u)})
+	}
+	span.SetAttributes(Attribute{Key: "http.request.resend_count", Value: max(r.attempts-1, 0)})
+	if resp != nil && resp.Resp != nil {
+		span.SetAttributes(Attribute{Key: "http.response.status_code", Value: resp.Resp.StatusCode})
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+	return resp, err
+}
+
+// startAttempt opens a span for a single attempt, propagates its trace context
+// and returns a function recording the outcome when the attempt finishes.
+func (r *Request) startAttempt(req *http.Request) (*http.Request, func(*Response, error)) {
+	tracer, meter := r.client.tracer, r.client.meter
+	if tracer == nil && meter == nil {
+		return req, func(*Response, error) {}
+	}
+
+	attrs := []Attribute{
+		{Key: "http.request.method", Value: req.Method},
+		{Key: "server.address", Value: req.URL.Hostname()},
+	}
+
+	var span Span
+	if tracer != nil {
+		var ctx context.Context
+		ctx, span = tracer.Start(req.Context(), "HTTP "+req.Method+" attempt",
+			append(attrs,
+				Attribute{Key: "url.full", Value: redactURL(req.URL)},
+				Attribute{Key: "http.request.attempt", Value: r.attempts},
+			)...,
+		)
+		req = req.WithContext(ctx)
+		if sc := span.SpanContext(); sc.IsValid() {
+			req.Header.Set("Traceparent", sc.TraceParent())
+		}
+	}
+
+	start := time.Now()
+	return req, func(resp *Response, err error) {
+		if resp != nil && resp.Resp != nil {
+			attrs = append(attrs, Attribute{Key: "http.response.status_code", Value: resp.Resp.StatusCode})
+		}
+		if err != nil {
+			attrs = append(attrs, Attribute{Key: "error.type", Value: errorKind(err)})
+		}
+
+		if span != nil {
+			span.SetAttributes(attrs[2:]...)
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.End()
+		}
+
+		if meter != nil {
+			ctx := req.Context()
+			meter.Record(ctx, MetricRequestDuration, time.Since(start).Seconds(), attrs...)
+			if req.ContentLength > 0 {
+				meter.Record(ctx, MetricRequestBodySize, float64(req.ContentLength), attrs...)
+			}
+			if size := responseBodySize(resp); size >= 0 {
+				meter.Record(ctx, MetricResponseBodySize, float64(size), attrs...)
+			}
+		}
+	}
+}
+
+// responseBodySize returns the known size of the response body, or -1.
+func responseBodySize(resp *Response) int64 {
+	if resp == nil || resp.Resp == nil {
+		return -1
+	}
+	if !resp.IsStream() && resp.err == nil && !resp.released {
+		return int64(len(resp.bodyBytes))
+	}
+	return resp.Resp.ContentLength
+}
+
+// errorKind returns a short, low-cardinality description of err.
+func errorKind(err error) string {
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		return strconv.Itoa(httpErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// maxDuration is the largest representable delay; backoffs saturate at it.
+const maxDuration = time.Duration(1<<63 - 1)
+
+// Backoff computes the delay to wait before the given retry attempt.
+// attempt starts at 1 for the first retry, and prev is the delay used before
+// the previous retry (zero for the first one).
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// ConstantBackoff waits the same delay before every retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(attempt int, prev time.Duration) time.Duration {
+		return delay
+	}
+}
+
+// LinearBackoff waits base, 2*base, 3*base, ... before successive retries.
+func LinearBackoff(base time.Duration) Backoff {
+	return func(attempt int, prev time.Duration) time.Duration {
+		return multiply(base, attempt)
+	}
+}
+
+// ExponentialBackoff waits base, 2*base, 4*base, ... before successive retries.
+func ExponentialBackoff(base time.Duration) Backoff {
+	return func(attempt int, prev time.Duration) time.Duration {
+		return exponential(base, attempt)
+	}
+}
+
+// ExponentialJitterBackoff waits a random delay between zero and the
+// exponential delay for the attempt ("full jitter"), which spreads out
+// retries from many concurrent clients.
+func ExponentialJitterBackoff(base time.Duration) Backoff {
+	return func(attempt int, prev time.Duration) time.Duration {
+		return randomBetween(0, exponential(base, attempt))
+	}
+}
+
+// DecorrelatedJitterBackoff waits a random delay between base and three times
+// the previous delay, capped at maxDelay. A maxDelay of zero means no cap.
+func DecorrelatedJitterBackoff(base, maxDelay time.Duration) Backoff {
+	return func(attempt int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		delay := randomBetween(base, multiply(prev, 3))
+		if maxDelay > 0 {
+			delay = min(delay, maxDelay)
+		}
+		return delay
+	}
+}
+
+// exponential returns base * 2^(attempt-1), saturating instead of overflowing.
+func exponential(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay = multiply(delay, 2)
+		if delay == maxDuration {
+			break
+		}
+	}
+	return delay
+}
+
+// multiply returns d * n for non-negative values, saturating at maxDuration.
+func multiply(d time.Duration, n int) time.Duration {
+	if n > 0 && d > maxDuration/time.Duration(n) {
+		return maxDuration
+	}
+	return d * time.Duration(n)
+}
+
+// randomBetween returns a random duration in [lo, hi] for 0 <= lo.
+func randomBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	span := hi - lo
+	if span < maxDuration {
+		// Include hi itself; the full range cannot be widened further.
+		span++
+	}
+	return lo + rand.N(span)
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a 429 or
+// 503 response. The header may hold a number of seconds or an HTTP date.
+func retryAfter(resp *requesto.Response) (time.Duration, bool) {
+	if resp == nil || resp.Resp == nil {
+		return 0, false
+	}
+	if code := resp.Resp.StatusCode; code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	return parseRetryAfter(resp.Resp.Header.Get("Retry-After"))
+}
+
+// parseRetryAfter parses the value of a Retry-After header.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,447 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// CachePolicy configures the HTTP caching middleware.
+type CachePolicy struct {
+	// Storage holds the cached responses. It defaults to NewMemoryCache(1000).
+	Storage CacheStorage
+	// KeyFunc computes the cache key of a request. It defaults to the full URL.
+	KeyFunc func(req *requesto.Request) string
+}
+
+// maxVariants caps the number of responses stored per key for different
+// values of the request headers named by Vary.
+const maxVariants = 16
+
+// cacheableStatus lists the status codes the cache stores.
+var cacheableStatus = []int{
+	http.StatusOK,
+	http.StatusNonAuthoritativeInfo,
+	http.StatusNoContent,
+	http.StatusMultipleChoices,
+	http.StatusMovedPermanently,
+	http.StatusPermanentRedirect,
+}
+
+// NewCache creates a middleware implementing a private HTTP cache as described
+// by RFC 7234. GET responses are stored according to Cache-Control and
+// Expires, with one variant per set of values of the headers named by Vary.
+// They are revalidated with If-None-Match and If-Modified-Since when stale,
+// and served stale while being revalidated in the background when the server
+// allows it with stale-while-revalidate. Successful unsafe requests (POST,
+// PUT, PATCH, DELETE) invalidate the cached entry for their URL.
+//
+// Cached responses are returned as regular *requesto.Response values.
+// Streaming responses are never cached.
+func NewCache(policy CachePolicy) requesto.Middleware {
+	if policy.Storage == nil {
+		policy.Storage = NewMemoryCache(1000)
+	}
+	if policy.KeyFunc == nil {
+		policy.KeyFunc = func(req *requesto.Request) string {
+			if u := req.URL(); u != nil {
+				return u.String()
+			}
+			return ""
+		}
+	}
+
+	c := &httpCache{
+		policy:       policy,
+		revalidating: make(map[string]bool),
+		locks:        make(map[string]*keyLock),
+	}
+	return c.handle
+}
+
+// httpCache holds the state of a cache middleware.
+type httpCache struct {
+	policy       CachePolicy
+	mu           sync.Mutex
+	revalidating map[string]bool
+	// locks serializes updates of each key, since storing a response merges
+	// it with the variants already stored under the same key.
+	locks map[string]*keyLock
+}
+
+// keyLock is a mutex shared by the goroutines updating one key.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockKey locks key for updating and returns the function unlocking it.
+func (c *httpCache) lockKey(key string) (unlock func()) {
+	c.mu.Lock()
+	l, ok := c.locks[key]
+	if !ok {
+		l = &keyLock{}
+		c.locks[key] = l
+	}
+	l.refs++
+	c.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		c.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(c.locks, key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// handle is the middleware function.
+func (c *httpCache) handle(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+	storage := c.policy.Storage
+	key := c.policy.KeyFunc(req)
+
+	switch req.Method() {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return next(req)
+	default:
+		resp, err := next(req)
+		if err == nil && resp != nil && resp.Resp != nil && resp.Resp.StatusCode < 400 {
+			unlock := c.lockKey(key)
+			storage.Delete(key)
+			unlock()
+		}
+		return resp, err
+	}
+
+	reqHeader := req.Header()
+	reqCC := parseCacheControl(reqHeader)
+	if _, ok := reqCC["no-store"]; ok {
+		return next(req)
+	}
+
+	entry, ok := storage.Get(key)
+	if ok {
+		entry, ok = entry.selectVariant(reqHeader)
+	}
+	if !ok {
+		return c.fetch(key, req, nil, next)
+	}
+
+	now := time.Now()
+	respCC := parseCacheControl(entry.Header)
+	age := entry.age(now)
+	lifetime := entry.freshnessLifetime(respCC)
+
+	_, reqNoCache := reqCC["no-cache"]
+	_, respNoCache := respCC["no-cache"]
+	mustValidate := reqNoCache || respNoCache
+	if maxAge, ok := directiveSeconds(reqCC, "max-age"); ok && age > maxAge {
+		mustValidate = true
+	}
+
+	if !mustValidate && age < lifetime {
+		return entry.response(req, age), nil
+	}
+
+	_, mustRevalidate := respCC["must-revalidate"]
+	if swr, ok := directiveSeconds(respCC, "stale-while-revalidate"); ok && !mustValidate && !mustRevalidate && age < lifetime+swr {
+		c.revalidate(key, req, entry, next)
+		return entry.response(req, age), nil
+	}
+
+	return c.fetch(key, req, entry, next)
+}
+
+// fetch sends the request, conditionally if a stale entry with validators is
+// available, and stores or refreshes the result.
+func (c *httpCache) fetch(key string, req *requesto.Request, entry *CacheEntry, next requesto.Next) (*requesto.Response, error) {
+	if entry != nil {
+		etag := entry.Header.Get("ETag")
+		lastModified := entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			// Send the conditional headers on a copy so the caller's request is untouched.
+			req = req.Clone(nil)
+			if etag != "" {
+				req.SetHeader("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				req.SetHeader("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := next(req)
+	if err != nil || resp == nil || resp.Resp == nil {
+		return resp, err
+	}
+
+	if resp.Resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Close()
+		refreshed := entry.refresh(resp.Resp.Header, requestTime, time.Now())
+		c.store(key, req.Header(), refreshed)
+		return refreshed.response(req, refreshed.age(time.Now())), nil
+	}
+
+	if resp.IsStream() || !isStorable(resp.Resp) {
+		return resp, nil
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return resp, nil
+	}
+	c.store(key, req.Header(), &CacheEntry{
+		StatusCode:   resp.Resp.StatusCode,
+		Header:       resp.Resp.Header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+		VaryHeader:   varyHeader(resp.Resp.Header, req.Header()),
+	})
+	return resp, nil
+}
+
+// store saves entry under key along with the variants already stored for it,
+// replacing the one that reqHeader selected. Variants that exceed maxVariants
+// are dropped, oldest first.
+func (c *httpCache) store(key string, reqHeader http.Header, entry *CacheEntry) {
+	defer c.lockKey(key)()
+
+	stored := *entry
+	stored.Variants = nil
+	if previous, ok := c.policy.Storage.Get(key); ok {
+		for _, variant := range previous.variants() {
+			if len(stored.Variants) == maxVariants-1 {
+				break
+			}
+			if !variant.varyMatches(reqHeader) {
+				stored.Variants = append(stored.Variants, variant)
+			}
+		}
+	}
+	c.policy.Storage.Set(key, &stored)
+}
+
+// revalidate refreshes a stale entry in the background, at most once per key.
+func (c *httpCache) revalidate(key string, req *requesto.Request, entry *CacheEntry, next requesto.Next) {
+	c.mu.Lock()
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	clone := req.Clone(context.WithoutCancel(req.Context()))
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+		// Nobody reads the answer, so release it in case it was not stored,
+		// for example because it is a stream.
+		if resp, _ := c.fetch(key, clone, entry, next); resp != nil {
+			resp.Close()
+		}
+	}()
+}
+
+// isStorable reports whether a response may be stored by a private cache.
+func isStorable(resp *http.Response) bool {
+	if !slices.Contains(cacheableStatus, resp.StatusCode) {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+	_, hasMaxAge := cc["max-age"]
+	return hasMaxAge ||
+		resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != ""
+}
+
+// response rebuilds a *requesto.Response from the entry as the answer to req.
+func (e *CacheEntry) response(req *requesto.Request, age time.Duration) *requesto.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	var httpReq *http.Request
+	if u := req.URL(); u != nil {
+		httpReq = &http.Request{
+			Method: req.Method(),
+			URL:    u,
+			Header: req.Header(),
+			Host:   u.Host,
+		}
+		httpReq = httpReq.WithContext(req.Context())
+	}
+	return requesto.NewResponse(&http.Response{
+		Request:       httpReq,
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	})
+}
+
+// refresh returns a copy of the entry updated with the headers of a 304 response.
+func (e *CacheEntry) refresh(header http.Header, requestTime, responseTime time.Time) *CacheEntry {
+	refreshed := *e
+	refreshed.Header = e.Header.Clone()
+	for key, values := range header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		refreshed.Header[key] = values
+	}
+	refreshed.RequestTime = requestTime
+	refreshed.ResponseTime = responseTime
+	return &refreshed
+}
+
+// variants returns the entry and the other variants stored with it, most
+// recent first.
+func (e *CacheEntry) variants() []*CacheEntry {
+	latest := *e
+	latest.Variants = nil
+	return append([]*CacheEntry{&latest}, e.Variants...)
+}
+
+// selectVariant returns the stored variant whose Vary headers match reqHeader.
+func (e *CacheEntry) selectVariant(reqHeader http.Header) (*CacheEntry, bool) {
+	for _, variant := range e.variants() {
+		if variant.varyMatches(reqHeader) {
+			return variant, true
+		}
+	}
+	return nil, false
+}
+
+// varyMatches reports whether the request headers match those the entry was
+// stored with, for every header named by Vary.
+func (e *CacheEntry) varyMatches(reqHeader http.Header) bool {
+	for _, name := range varyNames(e.Header) {
+		if !slices.Equal(e.VaryHeader.Values(name), reqHeader.Values(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// freshnessLifetime returns how long the entry is fresh after it was generated.
+func (e *CacheEntry) freshnessLifetime(cc map[string]string) time.Duration {
+	if maxAge, ok := directiveSeconds(cc, "max-age"); ok {
+		return maxAge
+	}
+	date := e.date()
+	if expires := e.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return t.Sub(date)
+	}
+	// Heuristic freshness: 10% of the time since the last modification.
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && date.After(lastModified) {
+		return date.Sub(lastModified) / 10
+	}
+	return 0
+}
+
+// age returns the current age of the entry as defined by RFC 7234 section 4.2.3.
+func (e *CacheEntry) age(now time.Time) time.Duration {
+	var ageValue time.Duration
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	apparentAge := max(0, e.ResponseTime.Sub(e.date()))
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparentAge, correctedAge) + now.Sub(e.ResponseTime)
+}
+
+// date returns the Date header of the entry, or the time it was received.
+func (e *CacheEntry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.ResponseTime
+}
+
+// varyHeader captures the request headers named by the response's Vary header.
+func varyHeader(respHeader, reqHeader http.Header) http.Header {
+	names := varyNames(respHeader)
+	if len(names) == 0 {
+		return nil
+	}
+	captured := make(http.Header, len(names))
+	for _, name := range names {
+		if values := reqHeader.Values(name); len(values) > 0 {
+			captured[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return captured
+}
+
+// varyNames returns the header names listed in the Vary header.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, value := range h.Values("Vary") {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// parseCacheControl parses the Cache-Control header into lower-cased
+// directives and their unquoted values.
+func parseCacheControl(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range h.Values("Cache-Control") {
+		for part := range strings.SplitSeq(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return directives
+}
+
+// directiveSeconds returns the value of a delta-seconds directive.
+func directiveSeconds(cc map[string]string, name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a stored response together with the metadata needed to
+// compute its freshness.
+type CacheEntry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// RequestTime and ResponseTime bracket the exchange that produced the entry.
+	RequestTime  time.Time `json:"request_time"`
+	ResponseTime time.Time `json:"response_time"`
+	// VaryHeader holds the request headers named by the response's Vary header.
+	VaryHeader http.Header `json:"vary_header,omitempty"`
+	// Variants holds older responses for the same key that were stored for
+	// other values of the headers named by Vary, most recent first.
+	Variants []*CacheEntry `json:"variants,omitempty"`
+}
+
+// CacheStorage stores cache entries by key. All the variants of a key are
+// kept in the single entry stored under it. Implementations must be safe for
+// concurrent use.
+type CacheStorage interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// MemoryCache is an in-memory CacheStorage that evicts the least recently
+// used entry once its capacity is reached.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// memoryItem is the value stored in the LRU list.
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache creates a MemoryCache holding at most capacity entries.
+// A capacity of zero or less defaults to 1000.
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used.
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryItem).entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry if needed.
+func (c *MemoryCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*memoryItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&memoryItem{key: key, entry: entry})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+// Delete removes the entry stored under key.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// DiskCache is a CacheStorage that keeps one JSON file per entry in a directory.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a DiskCache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get reads the entry stored under key. Unreadable entries are treated as misses.
+func (c *DiskCache) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set writes entry under key. The file is replaced atomically so concurrent
+// readers never observe a partial entry.
+func (c *DiskCache) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Delete removes the entry stored under key.
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// path returns the file used for key.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// ErrCircuitOpen is matched by the error returned when a request is rejected
+// because its circuit is open.
+var ErrCircuitOpen = errors.New("requesto: circuit breaker is open")
+
+// CircuitState is the state of a single circuit.
+type CircuitState int
+
+const (
+	// StateClosed lets every request through and counts failures.
+	StateClosed CircuitState = iota
+	// StateOpen rejects every request until the cool-down has elapsed.
+	StateOpen
+	// StateHalfOpen lets a limited number of trial requests through to decide
+	// whether the circuit closes again or reopens.
+	StateHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitOpenError is returned without sending the request when its circuit is open.
+// It matches ErrCircuitOpen with errors.Is.
+type CircuitOpenError struct {
+	Key string
+	// RetryAfter is the time left until the circuit lets a trial request through.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %q, retry in %v", ErrCircuitOpen, e.Key, e.RetryAfter)
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitBreakerPolicy configures the circuit breaker middleware.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. It defaults to 5.
+	FailureThreshold int
+	// CoolDown is how long the circuit stays open before allowing trial
+	// requests. It defaults to 30 seconds.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful trial requests needed to
+	// close the circuit again. It defaults to 1.
+	HalfOpenRequests int
+	// KeyFunc selects the circuit for a request. It defaults to the URL host.
+	KeyFunc func(req *requesto.Request) string
+	// IsFailure decides whether a result counts as a failure. By default any
+	// error and any 5xx response is a failure. Requests whose context was
+	// canceled by the caller are never counted, either way.
+	IsFailure func(resp *requesto.Response, err error) bool
+	// OnStateChange is called whenever a circuit changes state.
+	OnStateChange func(key string, from, to CircuitState)
+}
+
+// circuit holds the state of a single key.
+type circuit struct {
+	state     CircuitState
+	failures  int
+	successes int
+	inFlight  int
+	openedAt  time.Time
+}
+
+// NewCircuitBreaker creates a middleware that stops sending requests to a
+// failing downstream. Each key (by default each host) has its own circuit.
+//
+// Place it before NewRetrier in the chain so that retries are not attempted
+// while the circuit is open.
+func NewCircuitBreaker(policy CircuitBreakerPolicy) requesto.Middleware {
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = 5
+	}
+	if policy.CoolDown <= 0 {
+		policy.CoolDown = 30 * time.Second
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = 1
+	}
+	if policy.KeyFunc == nil {
+		policy.KeyFunc = hostKey
+	}
+	if policy.IsFailure == nil {
+		policy.IsFailure = func(resp *requesto.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			return resp != nil && resp.Resp != nil && resp.Resp.StatusCode >= 500
+		}
+	}
+
+	var mu sync.Mutex
+	circuits := make(map[string]*circuit)
+
+	// setState must be called with mu held. Callbacks are collected and run
+	// after the lock is released.
+	setState := func(key string, c *circuit, to CircuitState, notify *[]func()) {
+		from := c.state
+		if from == to {
+			return
+		}
+		c.state = to
+		c.failures, c.successes = 0, 0
+		if to == StateOpen {
+			c.openedAt = time.Now()
+		}
+		if policy.OnStateChange != nil {
+			*notify = append(*notify, func() { policy.OnStateChange(key, from, to) })
+		}
+	}
+	run := func(notify []func()) {
+		for _, fn := range notify {
+			fn()
+		}
+	}
+
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		key := policy.KeyFunc(req)
+		var notify []func()
+
+		// Decide whether the request may pass.
+		mu.Lock()
+		c, ok := circuits[key]
+		if !ok {
+			c = &circuit{}
+			circuits[key] = c
+		}
+		if c.state == StateOpen {
+			if wait := policy.CoolDown - time.Since(c.openedAt); wait > 0 {
+				mu.Unlock()
+				return nil, &CircuitOpenError{Key: key, RetryAfter: wait}
+			}
+			setState(key, c, StateHalfOpen, &notify)
+		}
+		if c.state == StateHalfOpen {
+			if c.inFlight >= policy.HalfOpenRequests-c.successes {
+				mu.Unlock()
+				run(notify)
+				return nil, &CircuitOpenError{Key: key}
+			}
+			c.inFlight++
+		}
+		trial := c.state == StateHalfOpen
+		mu.Unlock()
+		run(notify)
+
+		resp, err := next(req)
+		canceled := errors.Is(err, context.Canceled)
+		failed := !canceled && policy.IsFailure(resp, err)
+
+		// Record the outcome.
+		notify = nil
+		mu.Lock()
+		if trial && c.inFlight > 0 {
+			c.inFlight--
+		}
+		switch {
+		case canceled:
+			// A canceled request says nothing about the upstream, so it
+			// neither opens nor closes the circuit; it only frees its slot.
+		case c.state == StateClosed:
+			if failed {
+				c.failures++
+				if c.failures >= policy.FailureThreshold {
+					setState(key, c, StateOpen, &notify)
+				}
+			} else {
+				c.failures = 0
+			}
+		case c.state == StateHalfOpen:
+			if failed {
+				setState(key, c, StateOpen, &notify)
+			} else {
+				c.successes++
+				if c.successes >= policy.HalfOpenRequests {
+					setState(key, c, StateClosed, &notify)
+				}
+			}
+		}
+		mu.Unlock()
+		run(notify)
+
+		return resp, err
+	}
+}
+
+// hostKey returns the host of the request URL.
+func hostKey(req *requesto.Request) string {
+	if u := req.URL(); u != nil {
+		return u.Host
+	}
+	return ""
+}
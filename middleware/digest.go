@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// digestChallenge is a parsed "WWW-Authenticate: Digest" challenge together
+// with the nonce count used for it.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	nc        int
+}
+
+// NewDigestAuth creates a middleware implementing HTTP Digest authentication
+// (RFC 7616) with the MD5 and SHA-256 algorithms and the "auth" quality of
+// protection.
+//
+// When a server answers 401 with a Digest challenge, the request is resent
+// once with the computed Authorization header. The challenge is remembered per
+// host, so later requests are authorized without an extra round-trip.
+func NewDigestAuth(username, password string) requesto.Middleware {
+	var mu sync.Mutex
+	challenges := make(map[string]*digestChallenge)
+
+	authorize := func(req *requesto.Request, host string) bool {
+		mu.Lock()
+		challenge, ok := challenges[host]
+		var nc int
+		if ok {
+			challenge.nc++
+			nc = challenge.nc
+		}
+		mu.Unlock()
+		if !ok {
+			return false
+		}
+		header, err := challenge.authorization(username, password, req, nc)
+		if err != nil {
+			return false
+		}
+		req.SetHeader("Authorization", header)
+		return true
+	}
+
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		host := hostKey(req)
+		preemptive := authorize(req, host)
+
+		resp, err := next(req)
+		if resp == nil || resp.Resp == nil || resp.Resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+
+		challenge, stale, ok := parseDigestChallenge(resp.Resp.Header)
+		if !ok {
+			return resp, err
+		}
+		// A rejected preemptive authorization is only retried if the server
+		// reports that the nonce went stale; otherwise the credentials are wrong.
+		if preemptive && !stale {
+			return resp, err
+		}
+
+		mu.Lock()
+		challenges[host] = challenge
+		mu.Unlock()
+
+		if !authorize(req, host) {
+			return resp, err
+		}
+		resp.Close()
+		return next(req)
+	}
+}
+
+// parseDigestChallenge finds and parses the first supported Digest challenge
+// in the WWW-Authenticate headers, skipping those with an unsupported
+// algorithm or quality of protection. stale reports whether the server
+// flagged the previous nonce as stale.
+func parseDigestChallenge(h http.Header) (challenge *digestChallenge, stale bool, ok bool) {
+	var values []string
+	for _, value := range h.Values("WWW-Authenticate") {
+		values = append(values, splitChallenges(value)...)
+	}
+	for _, value := range values {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		challenge = &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		if challenge.nonce == "" {
+			continue
+		}
+		if challenge.algorithm == "" {
+			challenge.algorithm = "MD5"
+		}
+		if digestHash(challenge.algorithm) == nil {
+			continue
+		}
+		if qop, ok := params["qop"]; ok {
+			options := strings.Split(qop, ",")
+			for i := range options {
+				options[i] = strings.TrimSpace(options[i])
+			}
+			if !slices.Contains(options, "auth") {
+				// Only the "auth" quality of protection is supported.
+				continue
+			}
+			challenge.qop = "auth"
+		}
+		return challenge, strings.EqualFold(params["stale"], "true"), true
+	}
+	return nil, false, false
+}
+
+// splitChallenges splits a WWW-Authenticate value that lists several
+// challenges, such as `Digest algorithm=SHA-256, ..., Digest algorithm=MD5, ...`.
+// A challenge starts with a scheme token that is followed by a space rather
+// than by "=".
+func splitChallenges(value string) []string {
+	var challenges []string
+	start, quoted := 0, false
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ',':
+			rest := strings.TrimLeft(value[i+1:], " \t")
+			token := strings.IndexAny(rest, " =,")
+			if token > 0 && rest[token] == ' ' && !strings.HasPrefix(strings.TrimLeft(rest[token:], " "), "=") {
+				challenges = append(challenges, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(challenges, value[start:])
+}
+
+// parseAuthParams parses a comma-separated list of key=value pairs whose
+// values may be quoted strings containing commas.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+}
+
+// authorization computes the Authorization header value for req.
+func (c *digestChallenge) authorization(username, password string, req *requesto.Request, nc int) (string, error) {
+	algorithm := strings.ToUpper(c.algorithm)
+	newHash := digestHash(algorithm)
+	if newHash == nil {
+		return "", fmt.Errorf("requesto: unsupported digest algorithm %q", c.algorithm)
+	}
+	h := func(s string) string {
+		hasher := newHash()
+		hasher.Write([]byte(s))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	u := req.URL()
+	if u == nil {
+		return "", fmt.Errorf("requesto: cannot determine request URL")
+	}
+	uri := u.RequestURI()
+
+	cnonceBytes := make([]byte, 16)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method() + ":" + uri)
+
+	var response string
+	if c.qop != "" {
+		response = h(strings.Join([]string{ha1, c.nonce, ncValue, cnonce, c.qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	fields := []string{
+		"username=" + quoteString(username),
+		"realm=" + quoteString(c.realm),
+		"nonce=" + quoteString(c.nonce),
+		"uri=" + quoteString(uri),
+		"algorithm=" + c.algorithm,
+		"response=" + quoteString(response),
+	}
+	if c.qop != "" {
+		fields = append(fields, "qop="+c.qop, "nc="+ncValue, "cnonce="+quoteString(cnonce))
+	}
+	if c.opaque != "" {
+		fields = append(fields, "opaque="+quoteString(c.opaque))
+	}
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// digestHash returns the hash function of a digest algorithm, or nil if the
+// algorithm is not supported.
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+// quoteString formats s as an HTTP quoted-string (RFC 9110 section 5.6.4),
+// escaping only double quotes and backslashes.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
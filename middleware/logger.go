@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// redacted replaces sensitive values in log records.
+const redacted = "[REDACTED]"
+
+// loggerConfig is a private struct used to store the configuration for the NewLogger middleware.
+type loggerConfig struct {
+	logger        *slog.Logger
+	level         slog.Level
+	headers       bool
+	body          bool
+	maxBodySize   int
+	redactHeaders []string
+	redactParams  []string
+	redactFields  []string
+}
+
+// LoggerOption is a function type used to configure the NewLogger middleware.
+type LoggerOption func(*loggerConfig)
+
+// WithLogger sets the slog.Logger records are written to. It defaults to slog.Default().
+func WithLogger(logger *slog.Logger) LoggerOption {
+	return func(c *loggerConfig) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithLevel sets the level of records for successful requests. Client errors
+// (4xx) are always logged at Warn and failures or server errors (5xx) at Error.
+// It defaults to slog.LevelInfo.
+func WithLevel(level slog.Level) LoggerOption {
+	return func(c *loggerConfig) {
+		c.level = level
+	}
+}
+
+// WithHeaders controls whether request and response headers are logged.
+func WithHeaders(enabled bool) LoggerOption {
+	return func(c *loggerConfig) {
+		c.headers = enabled
+	}
+}
+
+// WithBody controls whether request and response bodies are logged.
+// Streaming response bodies are never read by the logger.
+func WithBody(enabled bool) LoggerOption {
+	return func(c *loggerConfig) {
+		c.body = enabled
+	}
+}
+
+// WithMaxBodySize caps the number of body bytes included in a record.
+// It defaults to 4096.
+func WithMaxBodySize(size int) LoggerOption {
+	return func(c *loggerConfig) {
+		if size > 0 {
+			c.maxBodySize = size
+		}
+	}
+}
+
+// WithRedactedHeaders adds headers whose values are replaced in records.
+// Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key are
+// always redacted.
+func WithRedactedHeaders(names ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.redactHeaders = append(c.redactHeaders, names...)
+	}
+}
+
+// WithRedactedParams sets query parameters whose values are replaced in logged URLs.
+func WithRedactedParams(names ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.redactParams = append(c.redactParams, names...)
+	}
+}
+
+// WithRedactedFields sets JSON object fields, at any nesting depth, and form
+// fields whose values are replaced in logged bodies. Matching is
+// case-insensitive. Once fields are set, bodies that are neither JSON nor
+// form-encoded are replaced as a whole, since they cannot be redacted.
+func WithRedactedFields(names ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.redactFields = append(c.redactFields, names...)
+	}
+}
+
+// NewLogger creates a middleware that emits one structured slog record per
+// request with its method, URL, status, duration, request and response sizes
+// and attempt number. Place it after NewRetrier in the chain to log every attempt, or
+// before it to log only the final outcome.
+func NewLogger(opts ...LoggerOption) requesto.Middleware {
+	config := &loggerConfig{
+		logger:        slog.Default(),
+		level:         slog.LevelInfo,
+		maxBodySize:   4096,
+		redactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		// Building the body up front caches it, so the request sends the
+		// same bytes that are logged. A body that cannot be built fails the
+		// request without sending it.
+		var reqBody []byte
+		var resp *requesto.Response
+		var err error
+		if config.body {
+			reqBody, err = req.BodyBytes()
+		}
+
+		start := time.Now()
+		if err == nil {
+			resp, err = next(req)
+		}
+		duration := time.Since(start)
+
+		ctx := req.Context()
+		level := config.level
+		status := 0
+		if resp != nil && resp.Resp != nil {
+			status = resp.Resp.StatusCode
+		}
+		switch {
+		case err != nil || status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		if !config.logger.Enabled(ctx, level) {
+			return resp, err
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Method()),
+			slog.String("url", config.redactURL(req.URL())),
+			slog.Duration("duration", duration),
+			slog.Int("attempt", req.Attempt()),
+		}
+		if size := requestSize(resp, reqBody); size >= 0 {
+			attrs = append(attrs, slog.Int64("request_size", size))
+		}
+		if status != 0 {
+			attrs = append(attrs,
+				slog.Int("status", status),
+				slog.Int64("response_size", responseSize(resp)),
+			)
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", config.formatError(req, err)))
+		}
+		if config.headers {
+			attrs = append(attrs, config.headerGroup("request_headers", req.Header()))
+			if status != 0 {
+				attrs = append(attrs, config.headerGroup("response_headers", resp.Resp.Header))
+			}
+		}
+		if config.body && len(reqBody) > 0 {
+			attrs = append(attrs, slog.String("request_body", config.formatBody(reqBody, req.Header().Get("Content-Type"))))
+		}
+		if config.body && status != 0 && !resp.IsStream() {
+			if body, readErr := resp.Bytes(); readErr == nil && len(body) > 0 {
+				attrs = append(attrs, slog.String("response_body", config.formatBody(body, resp.Header().Get("Content-Type"))))
+			}
+		}
+
+		config.logger.LogAttrs(ctx, level, "http request", attrs...)
+		return resp, err
+	}
+}
+
+// formatError renders err without the raw request URL, whose query may hold
+// secrets, and without the body snippet of an *requesto.HTTPError, which is
+// logged separately and redacted when body logging is enabled.
+func (c *loggerConfig) formatError(req *requesto.Request, err error) string {
+	var httpErr *requesto.HTTPError
+	if errors.As(err, &httpErr) {
+		return "requesto: " + httpErr.Method + " " + c.redactURL(req.URL()) + ": " + httpErr.Status
+	}
+	msg := err.Error()
+	if u := req.URL(); u != nil && u.RawQuery != "" {
+		msg = strings.ReplaceAll(msg, u.String(), c.redactURL(u))
+	}
+	return msg
+}
+
+// redactURL renders u with the configured query parameters redacted.
+func (c *loggerConfig) redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if len(c.redactParams) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+	query := u.Query()
+	for key, values := range query {
+		if containsFold(c.redactParams, key) {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	clone := *u
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// headerGroup renders headers as a slog group with sensitive values redacted.
+func (c *loggerConfig) headerGroup(name string, h http.Header) slog.Attr {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	attrs := make([]any, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(h[key], ", ")
+		if containsFold(c.redactHeaders, key) {
+			value = redacted
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return slog.Group(name, attrs...)
+}
+
+// formatBody redacts the configured fields in a JSON or form-encoded body and
+// truncates it to the maximum size. Other bodies are fully redacted when
+// fields are configured.
+func (c *loggerConfig) formatBody(body []byte, contentType string) string {
+	if len(c.redactFields) > 0 {
+		body = c.redactBody(body, contentType)
+	}
+	if len(body) > c.maxBodySize {
+		return string(body[:c.maxBodySize]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// redactBody replaces the values of configured fields in body.
+func (c *loggerConfig) redactBody(body []byte, contentType string) []byte {
+	var data any
+	if json.Unmarshal(body, &data) == nil {
+		if redactedBody, err := json.Marshal(c.redactJSON(data)); err == nil {
+			return redactedBody
+		}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for key, values := range form {
+				if containsFold(c.redactFields, key) {
+					for i := range values {
+						values[i] = redacted
+					}
+				}
+			}
+			return []byte(form.Encode())
+		}
+	}
+	return []byte(redacted)
+}
+
+// redactJSON replaces the values of configured fields in decoded JSON.
+func (c *loggerConfig) redactJSON(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if containsFold(c.redactFields, key) {
+				value[key] = redacted
+			} else {
+				value[key] = c.redactJSON(field)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = c.redactJSON(item)
+		}
+	}
+	return v
+}
+
+// requestSize returns the size of the request body: the logged body when it
+// was read, or else the Content-Length of the request that was sent, or -1.
+func requestSize(resp *requesto.Response, body []byte) int64 {
+	if body != nil {
+		return int64(len(body))
+	}
+	if resp != nil && resp.Resp != nil && resp.Resp.Request != nil {
+		return resp.Resp.Request.ContentLength
+	}
+	return -1
+}
+
+// responseSize returns the size of the response body, using the buffered
+// body when available and the Content-Length otherwise.
+func responseSize(resp *requesto.Response) int64 {
+	if !resp.IsStream() {
+		if body, err := resp.Bytes(); err == nil {
+			return int64(len(body))
+		}
+	}
+	return resp.Resp.ContentLength
+}
+
+// containsFold reports whether names contains name, ignoring case.
+func containsFold(names []string, name string) bool {
+	return slices.ContainsFunc(names, func(n string) bool {
+		return strings.EqualFold(n, name)
+	})
+}
@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// OAuth2 grant types supported by NewOAuth2.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+	GrantPassword          = "password"
+)
+
+// OAuth2Config configures how tokens are obtained from a token endpoint.
+type OAuth2Config struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID and ClientSecret authenticate the client using HTTP Basic
+	// authentication, or in the request body if AuthInBody is set.
+	ClientID     string
+	ClientSecret string
+	AuthInBody   bool
+	// Scopes are requested with every grant.
+	Scopes []string
+	// GrantType is one of GrantClientCredentials (the default),
+	// GrantRefreshToken or GrantPassword.
+	GrantType string
+	// Username and Password are the resource owner credentials for GrantPassword.
+	Username string
+	Password string
+	// RefreshToken is the initial refresh token for GrantRefreshToken.
+	RefreshToken string
+	// EndpointParams are additional form values sent to the token endpoint.
+	EndpointParams map[string]string
+	// ExpiryDelta refreshes tokens this long before they expire.
+	// It defaults to 10 seconds.
+	ExpiryDelta time.Duration
+	// Client sends the token requests. It defaults to a new requesto.Client.
+	Client *requesto.Client
+}
+
+// OAuth2Token is an access token returned by a token endpoint.
+type OAuth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	// Expiry is the time the token expires, or zero if it does not expire.
+	Expiry time.Time
+}
+
+// OAuth2Error is returned when the token endpoint rejects a grant.
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+// Error implements the error interface.
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("requesto: oauth2 token request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// OAuth2Source obtains and caches tokens. It is safe for concurrent use:
+// concurrent callers needing a new token wait for a single token request.
+type OAuth2Source struct {
+	config OAuth2Config
+
+	// sem is held while a token request is in flight.
+	sem   chan struct{}
+	mu    sync.Mutex
+	token *OAuth2Token
+}
+
+// NewOAuth2Source creates a token source from config.
+func NewOAuth2Source(config OAuth2Config) *OAuth2Source {
+	if config.GrantType == "" {
+		config.GrantType = GrantClientCredentials
+	}
+	if config.ExpiryDelta <= 0 {
+		config.ExpiryDelta = 10 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = requesto.NewClient(config.TokenURL)
+	}
+	s := &OAuth2Source{
+		config: config,
+		sem:    make(chan struct{}, 1),
+	}
+	if config.RefreshToken != "" {
+		s.token = &OAuth2Token{RefreshToken: config.RefreshToken}
+	}
+	return s
+}
+
+// Token returns a valid token, requesting a new one if the cached token is
+// missing or about to expire.
+func (s *OAuth2Source) Token(ctx context.Context) (*OAuth2Token, error) {
+	if token := s.valid(); token != nil {
+		return token, nil
+	}
+
+	// Only one goroutine requests a token; the others wait for it.
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	if token := s.valid(); token != nil {
+		return token, nil
+	}
+	return s.fetch(ctx)
+}
+
+// Invalidate discards the cached access token if it is still the given one,
+// so that the next call to Token requests a new one. The refresh token is kept.
+func (s *OAuth2Source) Invalidate(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && s.token.AccessToken == accessToken {
+		s.token = &OAuth2Token{RefreshToken: s.token.RefreshToken}
+	}
+}
+
+// valid returns the cached token if it can still be used.
+func (s *OAuth2Source) valid() *OAuth2Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil || s.token.AccessToken == "" {
+		return nil
+	}
+	if !s.token.Expiry.IsZero() && time.Now().Add(s.config.ExpiryDelta).After(s.token.Expiry) {
+		return nil
+	}
+	return s.token
+}
+
+// fetch requests a new token, preferring the refresh token when one is known.
+func (s *OAuth2Source) fetch(ctx context.Context) (*OAuth2Token, error) {
+	s.mu.Lock()
+	var refreshToken string
+	if s.token != nil {
+		refreshToken = s.token.RefreshToken
+	}
+	s.mu.Unlock()
+
+	var token *OAuth2Token
+	var err error
+	if refreshToken != "" {
+		token, err = s.request(ctx, map[string]string{
+			"grant_type":    GrantRefreshToken,
+			"refresh_token": refreshToken,
+		})
+		// Fall back to the configured grant if the refresh token was rejected.
+		if err != nil && s.config.GrantType != GrantRefreshToken {
+			token, err = nil, nil
+		}
+	}
+	if token == nil && err == nil {
+		switch s.config.GrantType {
+		case GrantClientCredentials:
+			token, err = s.request(ctx, map[string]string{"grant_type": GrantClientCredentials})
+		case GrantPassword:
+			token, err = s.request(ctx, map[string]string{
+				"grant_type": GrantPassword,
+				"username":   s.config.Username,
+				"password":   s.config.Password,
+			})
+		case GrantRefreshToken:
+			err = errors.New("requesto: oauth2 refresh_token grant requires a refresh token")
+		default:
+			err = fmt.Errorf("requesto: unsupported oauth2 grant type %q", s.config.GrantType)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the previous refresh token if the server did not issue a new one.
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	return token, nil
+}
+
+// tokenResponse is the JSON body returned by a token endpoint.
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	RefreshToken     string      `json:"refresh_token"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// request sends a grant to the token endpoint.
+func (s *OAuth2Source) request(ctx context.Context, form map[string]string) (*OAuth2Token, error) {
+	if len(s.config.Scopes) > 0 {
+		form["scope"] = strings.Join(s.config.Scopes, " ")
+	}
+	for key, value := range s.config.EndpointParams {
+		form[key] = value
+	}
+
+	req := s.config.Client.NewRequestWithContext(ctx).SetURL(s.config.TokenURL)
+	if s.config.AuthInBody {
+		form["client_id"] = s.config.ClientID
+		if s.config.ClientSecret != "" {
+			form["client_secret"] = s.config.ClientSecret
+		}
+	} else if s.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+	req.SetHeader("Accept", "application/json").SetFormData(form)
+
+	resp, err := req.Post()
+	if err != nil && resp == nil {
+		return nil, err
+	}
+	// Release the connection even when the token client streams responses.
+	defer resp.Close()
+
+	var body tokenResponse
+	decodeErr := resp.Unmarshal(&body)
+	if resp.Resp.StatusCode >= 400 || body.Error != "" {
+		return nil, &OAuth2Error{
+			StatusCode:  resp.Resp.StatusCode,
+			Code:        body.Error,
+			Description: body.ErrorDescription,
+		}
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("requesto: oauth2 token response has no access_token")
+	}
+
+	token := &OAuth2Token{
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+	}
+	if seconds, err := body.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// authorization returns the Authorization header value for the token.
+func (t *OAuth2Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// NewOAuth2 creates a middleware that authorizes requests with tokens from a
+// new OAuth2Source. See NewOAuth2WithSource.
+func NewOAuth2(config OAuth2Config) requesto.Middleware {
+	return NewOAuth2WithSource(NewOAuth2Source(config))
+}
+
+// NewOAuth2WithSource creates a middleware that sets the Authorization header
+// from source. Tokens are refreshed before they expire and, if the server
+// still answers 401, the token is discarded and the request is resent once
+// with a new one.
+func NewOAuth2WithSource(source *OAuth2Source) requesto.Middleware {
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		token, err := source.Token(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.SetHeader("Authorization", token.authorization())
+
+		resp, err := next(req)
+		if resp == nil || resp.Resp == nil || resp.Resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+
+		source.Invalidate(token.AccessToken)
+		fresh, tokenErr := source.Token(req.Context())
+		if tokenErr != nil || fresh.AccessToken == token.AccessToken {
+			return resp, err
+		}
+		resp.Close()
+		req.SetHeader("Authorization", fresh.authorization())
+		return next(req)
+	}
+}
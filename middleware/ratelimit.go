@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// RateLimitPolicy configures the rate limiting middleware.
+//
+// A limiter keeps one token bucket per key. To enforce both a global and a
+// per-host limit, chain two limiters:
+//
+//	client.Use(
+//		middleware.NewRateLimiter(middleware.RateLimitPolicy{Rate: 100, KeyFunc: middleware.GlobalKey}),
+//		middleware.NewRateLimiter(middleware.RateLimitPolicy{Rate: 10}),
+//	)
+type RateLimitPolicy struct {
+	// Rate is the number of requests per second allowed for each key.
+	// Zero or less disables the fixed rate, leaving only adaptive limits.
+	Rate float64
+	// Burst is the bucket capacity. It defaults to max(1, ceil(Rate)).
+	Burst int
+	// KeyFunc selects the bucket for a request. It defaults to the URL host;
+	// use GlobalKey to share one bucket between all requests.
+	KeyFunc func(req *requesto.Request) string
+	// Adaptive pauses a bucket when responses carry a Retry-After header on
+	// 429 or 503, or report an exhausted quota through X-RateLimit-Remaining
+	// and X-RateLimit-Reset.
+	Adaptive bool
+}
+
+// GlobalKey is a KeyFunc that puts every request into the same bucket.
+func GlobalKey(req *requesto.Request) string {
+	return ""
+}
+
+// tokenBucket is a token bucket that can additionally be paused until a
+// point in time.
+type tokenBucket struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		switch {
+		case now.Before(b.blockedUntil):
+			delay = b.blockedUntil.Sub(now)
+		case b.rate <= 0:
+			b.mu.Unlock()
+			return nil
+		default:
+			b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens--
+				b.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pauseUntil blocks the bucket until t, unless it is already blocked longer.
+func (b *tokenBucket) pauseUntil(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.After(b.blockedUntil) {
+		b.blockedUntil = t
+	}
+}
+
+// NewRateLimiter creates a middleware that delays requests so that each key
+// stays within the configured rate. Waiting is aborted with the context error
+// as soon as the request's context is done.
+func NewRateLimiter(policy RateLimitPolicy) requesto.Middleware {
+	if policy.Burst <= 0 {
+		policy.Burst = max(1, int(math.Ceil(policy.Rate)))
+	}
+	if policy.KeyFunc == nil {
+		policy.KeyFunc = hostKey
+	}
+
+	var mu sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		key := policy.KeyFunc(req)
+
+		mu.Lock()
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &tokenBucket{
+				rate:   policy.Rate,
+				burst:  float64(policy.Burst),
+				tokens: float64(policy.Burst),
+				last:   time.Now(),
+			}
+			buckets[key] = bucket
+		}
+		mu.Unlock()
+
+		if err := bucket.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := next(req)
+		if policy.Adaptive {
+			if until, ok := rateLimitPause(resp); ok {
+				bucket.pauseUntil(until)
+			}
+		}
+		return resp, err
+	}
+}
+
+// rateLimitPause reports until when the server asked the client to back off.
+func rateLimitPause(resp *requesto.Response) (time.Time, bool) {
+	if resp == nil || resp.Resp == nil {
+		return time.Time{}, false
+	}
+	if wait, ok := retryAfter(resp); ok {
+		return time.Now().Add(wait), true
+	}
+
+	header := resp.Resp.Header
+	if strings.TrimSpace(header.Get("X-RateLimit-Remaining")) != "0" {
+		return time.Time{}, false
+	}
+	reset, err := strconv.ParseFloat(strings.TrimSpace(header.Get("X-RateLimit-Reset")), 64)
+	if err != nil || reset < 0 {
+		return time.Time{}, false
+	}
+	// Servers send either a Unix timestamp or a number of seconds to wait.
+	if reset > 1e9 {
+		return time.Unix(0, int64(reset*float64(time.Second))), true
+	}
+	return time.Now().Add(time.Duration(reset * float64(time.Second))), true
+}
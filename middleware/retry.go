@@ -1,20 +1,39 @@
 package middleware
 
 import (
-	"log"
+	"errors"
 	"time"
 
 	"github.com/Kaguya233qwq/requesto"
 )
 
 // RetryPolicy defines the strategy for retrying a failed request.
+//
+// When status checking is enabled on the client or request, RetryIf receives
+// an *requesto.HTTPError for 4xx and 5xx responses, which can be inspected with
+// errors.As to retry on specific status codes.
 type RetryPolicy struct {
 	RetryCount   int
 	RetryBackoff time.Duration
 	RetryIf      func(resp *requesto.Response, err error) bool
+
+	// Backoff computes the delay before each retry.
+	// It defaults to ConstantBackoff(RetryBackoff).
+	Backoff Backoff
+	// MaxDelay caps every delay, including those requested by Retry-After.
+	// Zero means no cap.
+	MaxDelay time.Duration
+	// IgnoreRetryAfter disables honoring the Retry-After header of 429 and
+	// 503 responses, which otherwise replaces the computed backoff.
+	IgnoreRetryAfter bool
+	// OnRetry is called before waiting for each retry with the attempt number
+	// (starting at 1), the chosen delay and the result of the failed attempt.
+	OnRetry func(attempt int, delay time.Duration, resp *requesto.Response, err error)
 }
 
 // NewRetrier creates a new retry middleware based on the provided policy.
+// Waiting between attempts is aborted as soon as the request's context is done,
+// in which case the context error is returned.
 func NewRetrier(policy RetryPolicy) requesto.Middleware {
 	if policy.RetryIf == nil {
 		// Default retry condition is to retry on any error.
@@ -28,27 +47,55 @@ func NewRetrier(policy RetryPolicy) requesto.Middleware {
 	if policy.RetryBackoff <= 0 {
 		policy.RetryBackoff = 1 * time.Second
 	}
+	if policy.Backoff == nil {
+		policy.Backoff = ConstantBackoff(policy.RetryBackoff)
+	}
 
 	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
 		var resp *requesto.Response
 		var err error
+		var delay time.Duration
+		ctx := req.Context()
 
 		// The loop runs for the initial attempt + RetryCount retries.
 		for i := 0; i < policy.RetryCount+1; i++ {
 			resp, err = next(req)
 
-			// If the retry condition is not met, return the result immediately.
-			if !policy.RetryIf(resp, err) {
+			// If the retry condition is not met or the body cannot be sent
+			// again, return the result immediately.
+			if errors.Is(err, requesto.ErrBodyNotReplayable) || !policy.RetryIf(resp, err) {
 				return resp, err
 			}
 
-			// If this was the final attempt, break the loop and return the last result.
-			if i == policy.RetryCount {
+			// If this was the final attempt or the request was canceled,
+			// return the last result.
+			if i == policy.RetryCount || ctx.Err() != nil {
 				break
 			}
 
-			log.Printf("[Retry] Request failed (%v), retrying in %v...", err, policy.RetryBackoff)
-			time.Sleep(policy.RetryBackoff)
+			delay = policy.Backoff(i+1, delay)
+			if wait, ok := retryAfter(resp); ok && !policy.IgnoreRetryAfter {
+				delay = wait
+			}
+			if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+				delay = policy.MaxDelay
+			}
+			if policy.OnRetry != nil {
+				policy.OnRetry(i+1, delay, resp, err)
+			}
+
+			// Release the failed response before trying again.
+			if resp != nil {
+				resp.Close()
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
 		}
 
 		return resp, err
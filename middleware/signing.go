@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// SigningRequest is the final form of a request handed to a Signer.
+// Signers add their headers to Header, or query parameters to URL.
+type SigningRequest struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+	// BodyHash is the lowercase hex SHA-256 digest of Body.
+	BodyHash string
+	// Time is the signing time.
+	Time time.Time
+}
+
+// Signer signs outgoing requests.
+type Signer interface {
+	Sign(sr *SigningRequest) error
+}
+
+// SignerFunc adapts an ordinary function to the Signer interface.
+type SignerFunc func(sr *SigningRequest) error
+
+// Sign calls f(sr).
+func (f SignerFunc) Sign(sr *SigningRequest) error {
+	return f(sr)
+}
+
+// NewSigner creates a middleware that signs every request with signer.
+// The signer sees the final method, URL, headers and body of the request.
+// Place it after NewRetrier in the chain so that every attempt gets a fresh
+// signature.
+func NewSigner(signer Signer) requesto.Middleware {
+	return func(req *requesto.Request, next requesto.Next) (*requesto.Response, error) {
+		body, err := req.BodyBytes()
+		if err != nil {
+			return nil, err
+		}
+		u := req.URL()
+		if u == nil {
+			// Let the request fail with its own error.
+			return next(req)
+		}
+
+		sum := sha256.Sum256(body)
+		original := req.Header()
+		signedURL := *u
+		sr := &SigningRequest{
+			Method:   req.Method(),
+			URL:      &signedURL,
+			Header:   original.Clone(),
+			Body:     body,
+			BodyHash: hex.EncodeToString(sum[:]),
+			Time:     time.Now(),
+		}
+		if err := signer.Sign(sr); err != nil {
+			return nil, err
+		}
+
+		for key, values := range sr.Header {
+			if !slices.Equal(values, original[key]) {
+				req.SetHeader(key, strings.Join(values, ", "))
+			}
+		}
+		if signed := sr.URL.String(); signed != u.String() {
+			// The URL of a Unix socket request does not name the socket.
+			socket := req.UnixSocket()
+			req.SetURL(signed).SetUnixSocket(socket)
+		}
+		return next(req)
+	}
+}
+
+// HMACSigner signs requests with HMAC-SHA256 over the method, request URI,
+// timestamp, selected headers and body hash. The string to sign is:
+//
+//	METHOD "\n" REQUEST-URI "\n" TIMESTAMP "\n" name:value "\n" ... BODY-HASH
+//
+// with one lowercase name:value line per signed header, and the result is
+// sent as "HMAC-SHA256 KeyId=..., SignedHeaders=..., Signature=<base64>".
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+	// Header receives the signature. It defaults to "Authorization".
+	Header string
+	// TimestampHeader receives the RFC 3339 signing time and is always signed.
+	// It defaults to "X-Date".
+	TimestampHeader string
+	// SignedHeaders lists additional headers to include in the signature.
+	SignedHeaders []string
+}
+
+// Sign implements Signer.
+func (s *HMACSigner) Sign(sr *SigningRequest) error {
+	header := s.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	timestampHeader := s.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = "X-Date"
+	}
+	timestamp := sr.Time.UTC().Format(time.RFC3339)
+	sr.Header.Set(timestampHeader, timestamp)
+
+	names := []string{strings.ToLower(timestampHeader)}
+	for _, name := range s.SignedHeaders {
+		names = append(names, strings.ToLower(name))
+	}
+
+	var b strings.Builder
+	b.WriteString(sr.Method + "\n")
+	b.WriteString(sr.URL.RequestURI() + "\n")
+	b.WriteString(timestamp + "\n")
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(sr.Header.Get(name)) + "\n")
+	}
+	b.WriteString(sr.BodyHash)
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(b.String()))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sr.Header.Set(header, "HMAC-SHA256 KeyId="+s.KeyID+", SignedHeaders="+strings.Join(names, ";")+", Signature="+signature)
+	return nil
+}
@@ -0,0 +1,373 @@
+// Package mock provides an http.RoundTripper that serves canned responses,
+// allowing code built on requesto.Client to be tested without a network.
+//
+// Install it with requesto.WithTransport:
+//
+//	transport := mock.NewTransport()
+//	transport.On("GET", "/users/*").ReplyJSON(200, map[string]any{"name": "Alice"})
+//	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+//	...
+//	transport.AssertExpectations(t)
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ErrNoMatch is returned by Transport.RoundTrip when no expectation matches
+// the outgoing request.
+var ErrNoMatch = errors.New("mock: no expectation matches the request")
+
+// Transport is an http.RoundTripper that matches outgoing requests against a
+// list of expectations and answers them with canned responses.
+// Expectations are evaluated in the order they were registered.
+type Transport struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	unmatched    []string
+}
+
+// NewTransport creates a new Transport without any expectations.
+func NewTransport() *Transport {
+	return &Transport{
+		expectations: make([]*Expectation, 0),
+		unmatched:    make([]string, 0),
+	}
+}
+
+// On registers a new expectation for requests with the given method and URL
+// pattern and returns it for further configuration.
+//
+// An empty method matches any method. The pattern is matched with path.Match
+// semantics: if it contains "://" it is matched against the URL without its
+// query string, otherwise against the URL path only. "*" matches any URL.
+func (t *Transport) On(method, urlPattern string) *Expectation {
+	e := &Expectation{
+		mu:      &t.mu,
+		method:  strings.ToUpper(method),
+		pattern: urlPattern,
+		query:   make(url.Values),
+		headers: make(http.Header),
+		status:  http.StatusOK,
+		header:  make(http.Header),
+	}
+	t.mu.Lock()
+	t.expectations = append(t.expectations, e)
+	t.mu.Unlock()
+	return e
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	t.mu.Lock()
+	var matched *Expectation
+	for _, e := range t.expectations {
+		if e.exhausted() || !e.matches(req, body) {
+			continue
+		}
+		e.calls++
+		matched = e
+		break
+	}
+	if matched == nil {
+		t.unmatched = append(t.unmatched, req.Method+" "+req.URL.String())
+	}
+	t.mu.Unlock()
+
+	if matched == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, req.Method, req.URL)
+	}
+	return matched.respond(req)
+}
+
+// Reset removes all expectations and recorded calls.
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expectations = make([]*Expectation, 0)
+	t.unmatched = make([]string, 0)
+}
+
+// AssertExpectations reports a test error for every expectation that was not
+// called the expected number of times and for every request that matched no
+// expectation. It returns true if all expectations were met.
+func (t *Transport) AssertExpectations(tb testing.TB) bool {
+	tb.Helper()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ok := true
+	for _, e := range t.expectations {
+		switch {
+		case e.times > 0 && e.calls != e.times:
+			tb.Errorf("mock: expected %s to be called %d time(s), got %d", e, e.times, e.calls)
+			ok = false
+		case e.times == 0 && e.calls == 0:
+			tb.Errorf("mock: expected %s to be called, but it was not", e)
+			ok = false
+		}
+	}
+	for _, req := range t.unmatched {
+		tb.Errorf("mock: unexpected request %s", req)
+		ok = false
+	}
+	return ok
+}
+
+// Expectation describes a request to match and the response to return for it.
+// Its methods return the Expectation itself to allow chaining.
+type Expectation struct {
+	// mu is the owning transport's lock, which guards calls.
+	mu       *sync.Mutex
+	method   string
+	pattern  string
+	query    url.Values
+	headers  http.Header
+	body     func([]byte) bool
+	matchers []func(*http.Request) bool
+
+	status    int
+	header    http.Header
+	replyBody []byte
+	err       error
+	responder func(*http.Request) (*http.Response, error)
+
+	times int
+	calls int
+}
+
+// String returns a short description of the expectation for error messages.
+func (e *Expectation) String() string {
+	method := e.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + e.pattern
+}
+
+// WithQuery requires the request to carry the given query parameter value.
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.query.Add(key, value)
+	return e
+}
+
+// WithHeader requires the request to carry the given header value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.headers.Add(key, value)
+	return e
+}
+
+// WithBody requires the request body to equal the given string exactly.
+func (e *Expectation) WithBody(body string) *Expectation {
+	e.body = func(b []byte) bool {
+		return string(b) == body
+	}
+	return e
+}
+
+// WithJSONBody requires the request body to be JSON semantically equal to v.
+func (e *Expectation) WithJSONBody(v any) *Expectation {
+	expected, err := normalizeJSON(v)
+	e.body = func(b []byte) bool {
+		if err != nil {
+			return false
+		}
+		var actual any
+		if json.Unmarshal(b, &actual) != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, actual)
+	}
+	return e
+}
+
+// Match adds a custom predicate that the request must satisfy.
+func (e *Expectation) Match(fn func(req *http.Request) bool) *Expectation {
+	e.matchers = append(e.matchers, fn)
+	return e
+}
+
+// Times limits the expectation to n matches. Once it has been matched n
+// times it is skipped, and AssertExpectations checks that it was called
+// exactly n times. By default an expectation matches any number of times.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once is shorthand for Times(1).
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Reply sets the status code and body of the canned response.
+func (e *Expectation) Reply(status int, body string) *Expectation {
+	e.status = status
+	e.replyBody = []byte(body)
+	return e
+}
+
+// ReplyJSON sets the status code of the canned response and its body to the
+// JSON encoding of v. The Content-Type header is set to "application/json".
+func (e *Expectation) ReplyJSON(status int, v any) *Expectation {
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.err = fmt.Errorf("mock: cannot encode reply: %w", err)
+		return e
+	}
+	e.status = status
+	e.replyBody = data
+	e.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// ReplyHeader sets a header on the canned response.
+func (e *Expectation) ReplyHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// ReplyError makes the transport fail with err instead of returning a response.
+func (e *Expectation) ReplyError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// ReplyFunc builds the response dynamically from the matched request.
+func (e *Expectation) ReplyFunc(fn func(req *http.Request) (*http.Response, error)) *Expectation {
+	e.responder = fn
+	return e
+}
+
+// Calls returns the number of requests matched by the expectation so far.
+func (e *Expectation) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+// exhausted reports whether the expectation has reached its call limit.
+func (e *Expectation) exhausted() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+// matches reports whether req satisfies every condition of the expectation.
+func (e *Expectation) matches(req *http.Request, body []byte) bool {
+	if e.method != "" && e.method != req.Method {
+		return false
+	}
+	if !matchURL(e.pattern, req.URL) {
+		return false
+	}
+
+	query := req.URL.Query()
+	for key, values := range e.query {
+		for _, value := range values {
+			if !slices.Contains(query[key], value) {
+				return false
+			}
+		}
+	}
+	for key, values := range e.headers {
+		for _, value := range values {
+			if !slices.Contains(req.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+
+	if e.body != nil && !e.body(body) {
+		return false
+	}
+	for _, match := range e.matchers {
+		if !match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// respond builds the canned response for req.
+func (e *Expectation) respond(req *http.Request) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.responder != nil {
+		return e.responder(req)
+	}
+	return NewResponse(req, e.status, e.header.Clone(), e.replyBody), nil
+}
+
+// NewResponse builds an *http.Response for req with the given status code,
+// headers and body. It is useful when writing ReplyFunc callbacks.
+func NewResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// matchURL reports whether u matches the given pattern.
+func matchURL(pattern string, u *url.URL) bool {
+	if pattern == "*" || pattern == "" {
+		return true
+	}
+	target := u.Path
+	if strings.Contains(pattern, "://") {
+		stripped := *u
+		stripped.RawQuery = ""
+		stripped.Fragment = ""
+		target = stripped.String()
+	}
+	if pattern == target {
+		return true
+	}
+	ok, err := path.Match(pattern, target)
+	return err == nil && ok
+}
+
+// normalizeJSON round-trips v through JSON so it can be compared with a
+// decoded request body.
+func normalizeJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
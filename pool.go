@@ -0,0 +1,238 @@
+package requesto
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// WithMaxIdleConns limits the number of idle connections kept across all
+// hosts. Zero means no limit; the default is 100.
+func WithMaxIdleConns(n int) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithMaxIdleConns"); t != nil {
+			t.MaxIdleConns = n
+		}
+	}
+}
+
+// WithMaxIdleConnsPerHost limits the number of idle connections kept per
+// host. The default is 100.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithMaxIdleConnsPerHost"); t != nil {
+			t.MaxIdleConnsPerHost = n
+		}
+	}
+}
+
+// WithMaxConnsPerHost limits the total number of connections per host,
+// counting dialing, active and idle ones. Requests beyond the limit wait for
+// a connection to become available. Zero means no limit, the default.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithMaxConnsPerHost"); t != nil {
+			t.MaxConnsPerHost = n
+		}
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection is kept before being
+// closed. Zero means no limit; the default is 90 seconds.
+func WithIdleConnTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithIdleConnTimeout"); t != nil {
+			t.IdleConnTimeout = d
+		}
+	}
+}
+
+// WithKeepAlive sets the interval between TCP keep-alive probes of the
+// client's connections. A negative value disables them; the default is 30
+// seconds. It has no effect when a dialer is set with WithDialer or
+// WithDialContext.
+func WithKeepAlive(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if c.transport("WithKeepAlive") != nil {
+			c.dialer.keepAlive = d
+		}
+	}
+}
+
+// WithResponseHeaderTimeout limits the time to wait for the response headers
+// once the request has been written. Zero means no limit, the default.
+func WithResponseHeaderTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithResponseHeaderTimeout"); t != nil {
+			t.ResponseHeaderTimeout = d
+		}
+	}
+}
+
+// WithExpectContinueTimeout limits the time to wait for the server's first
+// response headers after sending the headers of a request with
+// "Expect: 100-continue". Zero sends the body immediately; the default is 1
+// second.
+func WithExpectContinueTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithExpectContinueTimeout"); t != nil {
+			t.ExpectContinueTimeout = d
+		}
+	}
+}
+
+// WithDisableCompression stops the transport from requesting gzip-compressed
+// responses and decompressing them transparently.
+func WithDisableCompression(disabled bool) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithDisableCompression"); t != nil {
+			t.DisableCompression = disabled
+		}
+	}
+}
+
+// Stats is a snapshot of the connection pool of a Client.
+type Stats struct {
+	// OpenConns is the number of open connections, active or idle.
+	OpenConns int
+	// ActiveConns is the number of connections in use by a request.
+	// An HTTP/2 connection counts as active for as long as it is open.
+	ActiveConns int
+	// IdleConns is the number of connections waiting in the pool.
+	IdleConns int
+	// Dials is the total number of connections opened.
+	Dials int64
+	// Requests is the total number of connections handed to requests.
+	Requests int64
+	// Reused is how many of those were reused from the pool.
+	Reused int64
+	// ReuseRatio is Reused divided by Requests, or 0 before the first request.
+	ReuseRatio float64
+}
+
+// Stats returns a snapshot of the client's connection pool. It is only
+// tracked for the transport created by NewClient; with a custom transport set
+// by WithTransport the zero value is returned.
+func (c *Client) Stats() Stats {
+	if c.stats == nil {
+		return Stats{}
+	}
+	return c.stats.snapshot()
+}
+
+// connStats gathers connection pool statistics from the client's dialer and
+// from httptrace events of each request.
+type connStats struct {
+	mu       sync.Mutex
+	open     int
+	idle     int
+	dials    int64
+	requests int64
+	reused   int64
+}
+
+// track wraps a newly dialed connection so that its closing is counted.
+func (s *connStats) track(conn net.Conn) net.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open++
+	s.dials++
+	return &trackedConn{Conn: conn, stats: s}
+}
+
+// clientTrace returns the hooks recording how a request obtained its
+// connection and when it returned it to the pool.
+func (s *connStats) clientTrace() *httptrace.ClientTrace {
+	var conn *trackedConn
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn = unwrapTrackedConn(info.Conn)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.requests++
+			if info.Reused {
+				s.reused++
+			}
+			if conn != nil && conn.idle {
+				conn.idle = false
+				s.idle--
+			}
+		},
+		PutIdleConn: func(err error) {
+			if err != nil || conn == nil {
+				return
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if !conn.idle && !conn.closed {
+				conn.idle = true
+				s.idle++
+			}
+		},
+	}
+}
+
+// withTrace attaches the statistics hooks to req.
+func (s *connStats) withTrace(req *http.Request) *http.Request {
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), s.clientTrace()))
+}
+
+// snapshot returns the current statistics.
+func (s *connStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{
+		OpenConns:   s.open,
+		ActiveConns: s.open - s.idle,
+		IdleConns:   s.idle,
+		Dials:       s.dials,
+		Requests:    s.requests,
+		Reused:      s.reused,
+	}
+	if s.requests > 0 {
+		stats.ReuseRatio = float64(s.reused) / float64(s.requests)
+	}
+	return stats
+}
+
+// trackedConn is a connection counted in connStats. Its state is guarded by
+// the stats mutex.
+type trackedConn struct {
+	net.Conn
+	stats  *connStats
+	idle   bool
+	closed bool
+}
+
+// Close closes the connection and removes it from the statistics.
+func (c *trackedConn) Close() error {
+	c.stats.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		c.stats.open--
+		if c.idle {
+			c.idle = false
+			c.stats.idle--
+		}
+	}
+	c.stats.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// unwrapTrackedConn returns the trackedConn under conn, looking through TLS
+// connections, or nil if conn was not dialed by the client.
+func unwrapTrackedConn(conn net.Conn) *trackedConn {
+	for conn != nil {
+		if tc, ok := conn.(*trackedConn); ok {
+			return tc
+		}
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = wrapper.NetConn()
+	}
+	return nil
+}
@@ -12,7 +12,9 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"strings"
+	"time"
 )
 
 // Next defines the next handler in the middleware chain.
@@ -34,7 +36,19 @@ type Request struct {
 	formData  map[string]string
 	bodyBytes []byte
 	files     map[string]File
-	err       error
+	offsets   map[string]int64 // start offsets of seekable file contents
+	stream    bool
+	raise     bool
+	errorType reflect.Type
+	trace     bool
+	proxy     *url.URL
+	socket    string
+	timeout   time.Duration
+	idleRead  time.Duration
+	attempts  int
+	// prepared caches the body built by BodyBytes so that it is reused as-is.
+	prepared *preparedBody
+	err      error
 }
 
 // JoinPath intelligently appends a path segment to the request's URL.
@@ -46,21 +60,24 @@ func (r *Request) JoinPath(p string) *Request {
 	}
 
 	// Check if p is an absolute URL.
-	parsedPath, err := url.Parse(p)
+	parsedPath, socket, err := parseURL(p)
 	if err == nil && parsedPath.IsAbs() {
 		// If it's an absolute URL, replace the current one.
-		r.url = parsedPath
+		r.url, r.socket = parsedPath, socket
 		return r
 	}
 
 	// Otherwise, perform the join logic.
 	if r.url == nil {
-		baseURL, err := url.Parse(r.client.BaseURL)
+		baseURL, socket, err := parseURL(r.client.BaseURL)
 		if err != nil {
 			r.err = err
 			return r
 		}
 		r.url = baseURL
+		if r.socket == "" {
+			r.socket = socket
+		}
 	}
 	r.url.Path = path.Join(r.url.Path, p)
 	return r
@@ -71,12 +88,12 @@ func (r *Request) SetURL(rawURL string) *Request {
 	if r.err != nil {
 		return r
 	}
-	u, err := url.Parse(rawURL)
+	u, socket, err := parseURL(rawURL)
 	if err != nil {
 		r.err = err
 		return r
 	}
-	r.url = u
+	r.url, r.socket = u, socket
 	return r
 }
 
@@ -101,6 +118,16 @@ func (r *Request) SetHeaders(h map[string]string) *Request {
 	return r
 }
 
+// SetHeader sets a single request header, replacing any existing values for
+// the key while keeping the other headers.
+func (r *Request) SetHeader(key, value string) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.headers.Set(key, value)
+	return r
+}
+
 // SetJsonData sets the request body to be JSON-encoded from the provided data (struct or map).
 // It also sets the Content-Type header to "application/json; charset=utf-8".
 func (r *Request) SetJsonData(data any) *Request {
@@ -108,6 +135,7 @@ func (r *Request) SetJsonData(data any) *Request {
 		return r
 	}
 	r.jsonData = data
+	r.prepared = nil
 
 	r.headers.Set("Content-Type", "application/json; charset=utf-8")
 	return r
@@ -121,6 +149,7 @@ func (r *Request) SetFormData(data map[string]string) *Request {
 	}
 
 	r.formData = data
+	r.prepared = nil
 	r.headers.Set("Content-Type", "application/x-www-form-urlencoded")
 	return r
 }
@@ -132,6 +161,7 @@ func (r *Request) SetBinary(data []byte) *Request {
 		return r
 	}
 	r.bodyBytes = data
+	r.prepared = nil
 	if r.headers.Get("Content-Type") == "" {
 		r.headers.Set("Content-Type", "application/octet-stream")
 	}
@@ -139,11 +169,64 @@ func (r *Request) SetBinary(data []byte) *Request {
 }
 
 // SetFiles sets the files to be uploaded as part of a multipart/form-data request.
+// File contents are closed once the request has finished; see File.
 func (r *Request) SetFiles(files map[string]File) *Request {
 	if r.err != nil {
 		return r
 	}
 	r.files = files
+	r.prepared = nil
+	return r
+}
+
+// SetStream enables or disables streaming mode for the request, overriding the
+// client default. In streaming mode the response body is left open and exposed
+// through Response.Body; the caller must consume it or call Response.Close.
+func (r *Request) SetStream(enabled bool) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.stream = enabled
+	return r
+}
+
+// SetRaiseForStatus controls whether the request returns an *HTTPError when
+// the response has a 4xx or 5xx status code, overriding the client default.
+// The response is still returned together with the error, so middleware such
+// as the retrier can inspect both. In streaming mode the body of a failed
+// response is closed once its first bytes have been read into HTTPError.Body;
+// reading the body of the returned response then fails with ErrReadingBody.
+func (r *Request) SetRaiseForStatus(enabled bool) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.raise = enabled
+	return r
+}
+
+// SetErrorResult registers the type used to decode the JSON body of 4xx and 5xx
+// responses, overriding the client default. v is only used for its type,
+// typically a pointer to a zero value such as &APIError{}.
+//
+// When the response indicates failure, a new value of that type is decoded
+// from the body and exposed through Response.ErrorResult, and the request
+// returns an *HTTPError whose Result field holds it. Unmarshal and ToStruct
+// then return that error instead of decoding into the success target.
+func (r *Request) SetErrorResult(v any) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.errorType = errorResultType(v)
+	return r
+}
+
+// SetTrace enables or disables timing collection for the request, overriding
+// the client default. The result is available through Response.TraceInfo.
+func (r *Request) SetTrace(enabled bool) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.trace = enabled
 	return r
 }
 
@@ -216,6 +299,97 @@ func (r *Request) Delete() (*Response, error) {
 	return r.send()
 }
 
+// Patch sets the method to PATCH and sends the request.
+func (r *Request) Patch() (*Response, error) {
+	r.method = "PATCH"
+	return r.send()
+}
+
+// Head sets the method to HEAD and sends the request.
+// The returned Response has an empty body.
+func (r *Request) Head() (*Response, error) {
+	r.method = "HEAD"
+	return r.send()
+}
+
+// Options sets the method to OPTIONS and sends the request.
+func (r *Request) Options() (*Response, error) {
+	r.method = "OPTIONS"
+	return r.send()
+}
+
+// Send sends the request using an arbitrary HTTP method, such as "PROPFIND"
+// or "PURGE". The method is normalized to upper case.
+func (r *Request) Send(method string) (*Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if method == "" {
+		return nil, errors.New("requesto: HTTP method must not be empty")
+	}
+	r.method = strings.ToUpper(method)
+	return r.send()
+}
+
+// Method returns the HTTP method of the request. It is empty until one of
+// the sending methods (Get, Post, Send, ...) is called.
+func (r *Request) Method() string {
+	return r.method
+}
+
+// URL returns a copy of the final URL the request is sent to, including the
+// merged query parameters, or nil if it cannot be determined.
+func (r *Request) URL() *url.URL {
+	if r.err != nil {
+		return nil
+	}
+	u, err := r.buildURL()
+	if err != nil {
+		return nil
+	}
+	clone := *u
+	return &clone
+}
+
+// Context returns the request's context.
+func (r *Request) Context() context.Context {
+	return r.ctx
+}
+
+// Attempt returns the number of times the request has been sent so far,
+// counting retries made by middleware.
+func (r *Request) Attempt() int {
+	return r.attempts
+}
+
+// Header returns a copy of the headers that will be sent with the request,
+// with the client defaults merged in. Use SetHeader to modify them.
+func (r *Request) Header() http.Header {
+	return r.buildHeaders()
+}
+
+// Clone returns a copy of the request bound to ctx. The copy shares the
+// client and the body data but can be modified and sent independently.
+// If ctx is nil, the original context is kept.
+func (r *Request) Clone(ctx context.Context) *Request {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	clone := *r
+	clone.ctx = ctx
+	clone.attempts = 0
+	clone.headers = r.headers.Clone()
+	clone.params = maps.Clone(r.params)
+	clone.formData = maps.Clone(r.formData)
+	clone.files = maps.Clone(r.files)
+	clone.offsets = maps.Clone(r.offsets)
+	if r.url != nil {
+		u := *r.url
+		clone.url = &u
+	}
+	return &clone
+}
+
 // Cookies parses and returns any cookies set in the request headers.
 func (r *Request) Cookies() []*http.Cookie {
 	dummyReq := &http.Request{Header: r.headers}
@@ -238,11 +412,14 @@ func (r *Request) buildURL() (*url.URL, error) {
 		if r.client.BaseURL == "" {
 			return nil, errors.New("requesto: no URL specified for the request and no BaseURL in client")
 		}
-		baseURL, err := url.Parse(r.client.BaseURL)
+		baseURL, socket, err := parseURL(r.client.BaseURL)
 		if err != nil {
 			return nil, err
 		}
 		r.url = baseURL
+		if r.socket == "" {
+			r.socket = socket
+		}
 	}
 
 	finalURL := r.url
@@ -325,6 +502,8 @@ func (r *Request) buildBody() (body io.Reader, contentType string, err error) {
 }
 
 // buildMultipartBody creates a multipart/form-data body for file uploads.
+// The body is fully buffered, so the standard library can replay it on
+// redirects, and files are reopened or rewound on every attempt.
 func (r *Request) buildMultipartBody(files map[string]File, formData map[string]string) (body io.Reader, contentType string, err error) {
 	bodyBuf := &bytes.Buffer{}
 	writer := multipart.NewWriter(bodyBuf)
@@ -335,18 +514,24 @@ func (r *Request) buildMultipartBody(files map[string]File, formData map[string]
 		}
 	}
 
+	if r.offsets == nil {
+		r.offsets = make(map[string]int64, len(files))
+	}
 	for fieldName, file := range files {
-		// If the content is an io.Closer (like *os.File), ensure it gets closed.
-		if closer, ok := file.Content.(io.Closer); ok {
-			defer closer.Close()
+		offset := r.offsets[fieldName]
+		content, release, err := file.open(&offset, r.attempts > 1)
+		r.offsets[fieldName] = offset
+		if err != nil {
+			return nil, "", err
 		}
+		defer release()
 
 		part, err := writer.CreateFormFile(fieldName, file.Name)
 		if err != nil {
 			return nil, "", err
 		}
 
-		if _, err = io.Copy(part, file.Content); err != nil {
+		if _, err = io.Copy(part, content); err != nil {
 			return nil, "", err
 		}
 	}
@@ -358,6 +543,54 @@ func (r *Request) buildMultipartBody(files map[string]File, formData map[string]
 	return bodyBuf, writer.FormDataContentType(), nil
 }
 
+// preparedBody is a request body built ahead of sending.
+type preparedBody struct {
+	data        []byte
+	contentType string
+}
+
+// BodyBytes builds the request body exactly as it will be sent and returns it.
+// The Content-Type header is updated accordingly. The built body is cached and
+// reused by every following attempt, so files are only read once; calling one
+// of the body setters discards it. A nil slice is returned for requests
+// without a body.
+//
+// This lets middleware such as request signers inspect the final body.
+func (r *Request) BodyBytes() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.prepared == nil {
+		body, contentType, err := r.buildBody()
+		if err != nil {
+			return nil, err
+		}
+		prepared := &preparedBody{contentType: contentType}
+		if body != nil {
+			if prepared.data, err = io.ReadAll(body); err != nil {
+				return nil, err
+			}
+		}
+		r.prepared = prepared
+	}
+	if r.prepared.contentType != "" {
+		r.headers.Set("Content-Type", r.prepared.contentType)
+	}
+	return r.prepared.data, nil
+}
+
+// body returns the reader and Content-Type for the next attempt, using the
+// prepared body when there is one.
+func (r *Request) body() (io.Reader, string, error) {
+	if r.prepared == nil {
+		return r.buildBody()
+	}
+	if r.prepared.data == nil {
+		return nil, r.prepared.contentType, nil
+	}
+	return bytes.NewReader(r.prepared.data), r.prepared.contentType, nil
+}
+
 // buildHeaders merges headers from the client and the request,
 // with request-level headers taking precedence.
 func (r *Request) buildHeaders() http.Header {
@@ -369,6 +602,9 @@ func (r *Request) buildHeaders() http.Header {
 // send executes the request by building and running the middleware chain.
 // All HTTP method functions (Get, Post, etc.) call this method.
 func (r *Request) send() (*Response, error) {
+	// Seekable file contents stay open for retries until the whole chain is done.
+	defer r.closeFiles()
+
 	var terminator Next = func(req *Request) (*Response, error) {
 		return req.do()
 	}
@@ -384,15 +620,29 @@ func (r *Request) send() (*Response, error) {
 		}(chain, m)
 	}
 
+	if r.client.tracer != nil {
+		return r.traceRequest(chain)
+	}
 	return chain(r)
 }
 
+// closeFiles closes the contents of the files uploaded by the request.
+func (r *Request) closeFiles() {
+	for _, file := range r.client.Files {
+		file.close()
+	}
+	for _, file := range r.files {
+		file.close()
+	}
+}
+
 // do is the final step in the middleware chain. It builds the http.Request,
 // sends it using the client's http.Client, and wraps the response.
 func (r *Request) do() (*Response, error) {
 	if r.err != nil {
 		return nil, r.err
 	}
+	r.attempts++
 
 	// Build the final URL.
 	finalURL, err := r.buildURL()
@@ -401,7 +651,7 @@ func (r *Request) do() (*Response, error) {
 	}
 
 	// Build the request body.
-	body, contentType, err := r.buildBody()
+	body, contentType, err := r.body()
 	if err != nil {
 		return nil, err
 	}
@@ -410,20 +660,86 @@ func (r *Request) do() (*Response, error) {
 		r.headers.Set("Content-Type", contentType)
 	}
 
+	ctx, err := r.withRequestProxy(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.socket != "" {
+		if !r.client.hooked {
+			return nil, errors.New("requesto: Unix socket URLs are not supported by the custom transport")
+		}
+		ctx = context.WithValue(ctx, socketContextKey{}, r.socket)
+	}
+
 	// Create the standard http.Request.
-	req, err := http.NewRequestWithContext(r.ctx, r.method, finalURL.String(), body)
+	req, err := http.NewRequestWithContext(ctx, r.method, finalURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
+	if r.client.stats != nil {
+		req = r.client.stats.withTrace(req)
+	}
 
 	// Merge headers.
 	req.Header = r.buildHeaders()
 
-	// Send the request.
-	resp, err := r.client.httpClient.Do(req)
+	req, finishAttempt := r.startAttempt(req)
+	req, finishTrace := r.startTrace(req)
+	req, finishTimeouts := r.startTimeouts(req)
+	response, err := r.roundTrip(req)
+	err = finishTimeouts(response, err)
+	finishTrace(response)
+	finishAttempt(response, err)
+	return response, err
+}
+
+// roundTrip sends the prepared http.Request and wraps the response, applying
+// the status checks configured on the request.
+func (r *Request) roundTrip(req *http.Request) (*Response, error) {
+	httpClient := r.client.httpClient
+	if httpClient.Timeout > 0 || r.socket != "" {
+		// startTimeouts enforces the client timeout through the request
+		// context, so that its expiry can be told apart from other errors.
+		override := *httpClient
+		override.Timeout = 0
+		if r.socket != "" {
+			override.Transport = &socketTransport{base: httpClient.Transport, socket: r.socket}
+		}
+		httpClient = &override
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	resp.Body = idleReadBody(resp.Body, r.idleRead)
+
+	var response *Response
+	if r.stream {
+		response = newStreamResponse(resp)
+	} else {
+		response = newResponse(resp)
+	}
 
-	return newResponse(resp), nil
+	if r.errorType != nil && response.IsError() {
+		response.decodeErrorResult(r.errorType)
+		return response, response.RaiseForStatus()
+	}
+	if r.raise && response.IsError() {
+		response.releaseErrorBody()
+		return response, response.RaiseForStatus()
+	}
+	return response, nil
+}
+
+// errorResultType returns the element type used to allocate error results.
+func errorResultType(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"maps"
 	"net/http"
+	"strings"
 )
 
 // bodyArgument is a marker interface used to identify different types of
@@ -70,28 +71,9 @@ func AsFiles(data map[string]File) bodyArgument {
 // It accepts optional arguments, which can be of type Params or Headers.
 // Body-related arguments are ignored. An error is returned for any unsupported argument type.
 func Get(URL string, args ...any) (*Response, error) {
-
-	client := NewClient(URL)
-	for _, arg := range args {
-		switch v := arg.(type) {
-		case Headers:
-			for key, values := range v {
-				client.Headers.Set(key, values)
-			}
-		case requestHeaders:
-			client.Headers = v.data
-		case queryParams:
-			client.Params = v.data
-		case Params:
-			maps.Copy(client.Params, v)
-		case JsonData, FormData, Files, jsonBody, formBody, filesBody:
-			// Ignore body-related arguments for a GET request.
-			continue
-		case nil:
-			continue
-		default:
-			return nil, fmt.Errorf("unsupported argument type for Get: %T", v)
-		}
+	client, err := newClientFromArgs("Get", URL, false, args)
+	if err != nil {
+		return nil, err
 	}
 	return client.Get()
 }
@@ -103,6 +85,89 @@ func Get(URL string, args ...any) (*Response, error) {
 // - Params: to set URL query parameters.
 // - AsJson, AsForm, or AsFiles: to set the request body.
 func Post(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Post", URL, true, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Post()
+}
+
+// Put sends a convenient PUT request.
+// It accepts the same optional arguments as Post.
+func Put(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Put", URL, true, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Put()
+}
+
+// Patch sends a convenient PATCH request.
+// It accepts the same optional arguments as Post.
+func Patch(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Patch", URL, true, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Patch()
+}
+
+// Delete sends a convenient DELETE request.
+// It accepts the same optional arguments as Post.
+func Delete(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Delete", URL, true, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Delete()
+}
+
+// Head sends a HEAD request to the specified URL.
+// It accepts the same optional arguments as Get; body-related arguments are ignored.
+func Head(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Head", URL, false, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Head()
+}
+
+// Options sends an OPTIONS request to the specified URL.
+// It accepts the same optional arguments as Get; body-related arguments are ignored.
+func Options(URL string, args ...any) (*Response, error) {
+	client, err := newClientFromArgs("Options", URL, false, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Options()
+}
+
+// Send sends a request with an arbitrary HTTP method to the specified URL.
+// It accepts the same optional arguments as Post; body-related arguments are
+// ignored for GET, HEAD and OPTIONS requests.
+func Send(method, URL string, args ...any) (*Response, error) {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return sendFromArgs(method, URL, false, args)
+	default:
+		return sendFromArgs(method, URL, true, args)
+	}
+}
+
+// sendFromArgs creates a one-off Client for Send and sends the request.
+func sendFromArgs(method, URL string, withBody bool, args []any) (*Response, error) {
+	client, err := newClientFromArgs("Send", URL, withBody, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.Send(method)
+}
+
+// newClientFromArgs creates a one-off Client for the given URL and applies the
+// optional arguments accepted by the package-level helpers. When withBody is
+// false, body-related arguments are silently ignored. The name is only used in
+// error messages.
+func newClientFromArgs(name, URL string, withBody bool, args []any) (*Client, error) {
 	client := NewClient(URL)
 
 	for _, arg := range args {
@@ -116,23 +181,37 @@ func Post(URL string, args ...any) (*Response, error) {
 		case bodyArgument:
 			// Handle different body argument types.
 			switch body := v.(type) {
-			case jsonBody:
-				client.JsonData = body.data
-			case formBody:
-				maps.Copy(client.FormData, body.data)
-			case filesBody:
-				maps.Copy(client.Files, body.data)
 			case queryParams:
 				client.Params = body.data
 			case requestHeaders:
 				client.Headers = body.data
+			case jsonBody:
+				if withBody {
+					client.JsonData = body.data
+				}
+			case formBody:
+				if withBody {
+					maps.Copy(client.FormData, body.data)
+				}
+			case filesBody:
+				if withBody {
+					maps.Copy(client.Files, body.data)
+				}
+			}
+		case FormData:
+			if withBody {
+				maps.Copy(client.FormData, v)
+			}
+		case Files:
+			if withBody {
+				maps.Copy(client.Files, v)
 			}
 		case nil:
 			continue
 		default:
-			return nil, fmt.Errorf("unsupported argument type for Post: %T", v)
+			return nil, fmt.Errorf("unsupported argument type for %s: %T", name, v)
 		}
 	}
 
-	return client.Post()
+	return client, nil
 }
@@ -0,0 +1,174 @@
+package requesto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// resolveConfig holds the DNS settings of the client's dialer.
+type resolveConfig struct {
+	// overrides maps "host:port" or "host" to a fixed address.
+	overrides map[string]string
+	resolver  *net.Resolver
+	cache     *dnsCache
+}
+
+// WithResolve pins host names to fixed addresses, like curl's --resolve,
+// without touching the system configuration. Keys are either "host:port",
+// which only applies to that port, or "host", which applies to all ports.
+// Values are an IP address, optionally with a port to connect to instead:
+//
+//	requesto.WithResolve(map[string]string{
+//		"api.example.com:443": "10.0.0.12",
+//		"cdn.example.com":     "10.0.0.20:8443",
+//	})
+//
+// TLS certificates are still verified against the original host name.
+// Calling it several times merges the maps.
+func WithResolve(overrides map[string]string) ClientOption {
+	return func(c *clientConfig) {
+		if c.transport("WithResolve") == nil {
+			return
+		}
+		if c.dialer.resolve.overrides == nil {
+			c.dialer.resolve.overrides = make(map[string]string)
+		}
+		for host, address := range overrides {
+			c.dialer.resolve.overrides[host] = address
+		}
+	}
+}
+
+// WithResolver sets the resolver used to look up host names, for example one
+// that queries a specific DNS server through its Dial function.
+func WithResolver(resolver *net.Resolver) ClientOption {
+	return func(c *clientConfig) {
+		if resolver != nil && c.transport("WithResolver") != nil {
+			c.dialer.resolve.resolver = resolver
+		}
+	}
+}
+
+// WithDNSCache caches the addresses of looked up host names for ttl, saving a
+// DNS round trip on every new connection. Entries are dropped early when no
+// cached address can be reached.
+func WithDNSCache(ttl time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if ttl > 0 && c.transport("WithDNSCache") != nil {
+			c.dialer.resolve.cache = &dnsCache{ttl: ttl, entries: make(map[string]dnsEntry)}
+		}
+	}
+}
+
+// dialTCP connects to address, applying the configured overrides, resolver
+// and cache. Addresses that need no special handling are dialed as-is.
+func (d *dialer) dialTCP(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return d.dialContext(ctx, network, address)
+	}
+
+	if override, ok := d.resolve.override(host, port); ok {
+		return d.dialContext(ctx, network, override)
+	}
+	if net.ParseIP(host) != nil || (d.resolve.resolver == nil && d.resolve.cache == nil) {
+		return d.dialContext(ctx, network, address)
+	}
+
+	addrs, err := d.resolve.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := d.dialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if d.resolve.cache != nil {
+		d.resolve.cache.delete(host)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// override returns the fixed address for host and port, if any.
+func (c *resolveConfig) override(host, port string) (string, bool) {
+	address, ok := c.overrides[net.JoinHostPort(host, port)]
+	if !ok {
+		address, ok = c.overrides[host]
+	}
+	if !ok {
+		return "", false
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, port)
+	}
+	return address, true
+}
+
+// lookup returns the addresses of host, from the cache when possible.
+func (c *resolveConfig) lookup(ctx context.Context, host string) ([]string, error) {
+	if c.cache != nil {
+		if addrs, ok := c.cache.get(host); ok {
+			return addrs, nil
+		}
+	}
+	resolver := c.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		c.cache.set(host, addrs)
+	}
+	return addrs, nil
+}
+
+// dnsCache is an in-process cache of host name lookups.
+type dnsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+// dnsEntry is a cached lookup result.
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// get returns the cached addresses of host unless they have expired.
+func (c *dnsCache) get(host string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[host]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.addrs, true
+}
+
+// set caches the addresses of host for the configured TTL.
+func (c *dnsCache) set(host string, addrs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+}
+
+// delete drops the cached addresses of host.
+func (c *dnsCache) delete(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, host)
+}
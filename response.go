@@ -1,10 +1,14 @@
 package requesto
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"reflect"
+	"sync"
 )
 
 // Response wraps the standard http.Response to provide convenient access to the
@@ -14,11 +18,40 @@ type Response struct {
 	Resp      *http.Response
 	bodyBytes []byte
 	err       error
+
+	// errorResult holds the decoded error body when an error result type was
+	// registered and the response indicates failure.
+	errorResult any
+	failed      bool
+
+	// traceInfo is set when timing collection was enabled for the request.
+	traceInfo *TraceInfo
+
+	// stream holds the live response body in streaming mode until it is
+	// drained by one of the body accessors or closed by the caller.
+	mu     sync.Mutex
+	stream io.ReadCloser
+
+	// released is set when the streaming body of a failed response was closed
+	// after its first bytes were kept in errorBody for the HTTPError.
+	released  bool
+	errorBody []byte
 }
 
 // newResponse creates a new Response instance. It reads the entire response
 // body into memory and closes it, making the body accessible for multiple reads.
+// Responses to HEAD requests never carry a body, so nothing is read for them.
 func newResponse(resp *http.Response) *Response {
+	if resp.Body == nil || isHeadResponse(resp) {
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		return &Response{
+			Resp:      resp,
+			bodyBytes: []byte{},
+		}
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 
@@ -29,6 +62,136 @@ func newResponse(resp *http.Response) *Response {
 	}
 }
 
+// NewResponse wraps a standard http.Response, reading its body into memory
+// and closing it. It is mainly useful for middleware that produce responses
+// without sending a request, such as caches.
+func NewResponse(resp *http.Response) *Response {
+	return newResponse(resp)
+}
+
+// newStreamResponse creates a new Response instance in streaming mode. The
+// body is left open and is only read into memory if Text, Bytes, Json or one
+// of the unmarshalling helpers is called.
+func newStreamResponse(resp *http.Response) *Response {
+	if resp.Body == nil || isHeadResponse(resp) {
+		return newResponse(resp)
+	}
+	return &Response{
+		Resp:   resp,
+		stream: resp.Body,
+	}
+}
+
+// isHeadResponse reports whether resp answers a HEAD request.
+func isHeadResponse(resp *http.Response) bool {
+	return resp.Request != nil && resp.Request.Method == http.MethodHead
+}
+
+// load drains and closes the streaming body, if any, so that the buffered
+// accessors can be used. It is a no-op for buffered responses.
+func (r *Response) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.released {
+		return errBodyReleased
+	}
+	if r.stream != nil {
+		body, err := io.ReadAll(r.stream)
+		r.stream.Close()
+		r.stream = nil
+		r.bodyBytes = body
+		if err != nil {
+			r.err = fmt.Errorf("%w: %w", ErrReadingBody, err)
+		}
+	}
+	return r.err
+}
+
+// releaseErrorBody reads the first bytes of the streaming body of a failed
+// response for the HTTPError and closes it, so that callers returning early on
+// the error do not leak the connection. The body accessors then fail with
+// ErrReadingBody rather than return a truncated body.
+func (r *Response) releaseErrorBody() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stream == nil {
+		return
+	}
+	r.errorBody, _ = io.ReadAll(io.LimitReader(r.stream, maxErrorBodySnippet))
+	r.stream.Close()
+	r.stream = nil
+	r.released = true
+}
+
+// IsStream reports whether the response body is still an unread stream.
+func (r *Response) IsStream() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stream != nil
+}
+
+// Body returns the response body as an io.ReadCloser.
+// In streaming mode this is the live network stream, which the caller must
+// close. Otherwise it is a reader over the buffered body, which is empty if
+// status checking closed the streaming body of a failed response.
+func (r *Response) Body() io.ReadCloser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stream != nil {
+		return r.stream
+	}
+	return io.NopCloser(bytes.NewReader(r.bodyBytes))
+}
+
+// Close releases the underlying streaming body without reading it.
+// It is safe to call on buffered responses and more than once.
+func (r *Response) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stream == nil {
+		return nil
+	}
+	err := r.stream.Close()
+	r.stream = nil
+	return err
+}
+
+// CopyTo writes the response body to w and returns the number of bytes written.
+// In streaming mode the body is copied directly from the network without being
+// buffered and is closed afterwards.
+func (r *Response) CopyTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	stream := r.stream
+	r.stream = nil
+	r.mu.Unlock()
+
+	if stream != nil {
+		defer stream.Close()
+		return io.Copy(w, stream)
+	}
+	if err := r.load(); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(r.bodyBytes)
+	return int64(n), err
+}
+
+// SaveTo writes the response body to the file at the given path, creating or
+// truncating it.
+func (r *Response) SaveTo(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if _, err := r.CopyTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 // StatusCode returns the HTTP status code of the response.
 // It returns -1 if an error occurred while reading the response body.
 func (r *Response) StatusCode() int {
@@ -38,6 +201,50 @@ func (r *Response) StatusCode() int {
 	return r.Resp.StatusCode
 }
 
+// IsError reports whether the response has a 4xx or 5xx status code.
+func (r *Response) IsError() bool {
+	return r.Resp != nil && r.Resp.StatusCode >= 400
+}
+
+// RaiseForStatus returns an *HTTPError if the response has a 4xx or 5xx
+// status code, and nil otherwise.
+func (r *Response) RaiseForStatus() error {
+	if !r.IsError() {
+		return nil
+	}
+	return newHTTPError(r)
+}
+
+// TraceInfo returns the timing breakdown of the request attempt that produced
+// the response. It is the zero value unless tracing was enabled with WithTrace
+// or Request.SetTrace.
+func (r *Response) TraceInfo() TraceInfo {
+	if r.traceInfo == nil {
+		return TraceInfo{}
+	}
+	return *r.traceInfo
+}
+
+// ErrorResult returns the value decoded from the body of a failed response
+// when an error result type was registered with SetErrorResult. It returns nil
+// otherwise, or if the body could not be decoded.
+func (r *Response) ErrorResult() any {
+	return r.errorResult
+}
+
+// decodeErrorResult decodes the body into a new value of type t and marks the
+// response as failed, so the success accessors refuse to decode it.
+func (r *Response) decodeErrorResult(t reflect.Type) {
+	r.failed = true
+	if err := r.load(); err != nil || len(r.bodyBytes) == 0 {
+		return
+	}
+	target := reflect.New(t).Interface()
+	if err := json.Unmarshal(r.bodyBytes, target); err == nil {
+		r.errorResult = target
+	}
+}
+
 // Header returns the response headers.
 func (r *Response) Header() http.Header {
 	if r.err != nil {
@@ -48,24 +255,24 @@ func (r *Response) Header() http.Header {
 
 // Text returns the response body as a string.
 func (r *Response) Text() (string, error) {
-	if r.err != nil {
-		return "", r.err
+	if err := r.load(); err != nil {
+		return "", err
 	}
 	return string(r.bodyBytes), nil
 }
 
 // Bytes returns the response body as a byte slice.
 func (r *Response) Bytes() ([]byte, error) {
-	if r.err != nil {
-		return nil, r.err
+	if err := r.load(); err != nil {
+		return nil, err
 	}
 	return r.bodyBytes, nil
 }
 
 // Json unmarshals the response body into a map[string]any.
 func (r *Response) Json() (json_results map[string]any, err error) {
-	if r.err != nil {
-		return json_results, r.err
+	if err := r.load(); err != nil {
+		return json_results, err
 	}
 	if len(r.bodyBytes) == 0 {
 		return json_results, nil
@@ -78,10 +285,14 @@ func (r *Response) Json() (json_results map[string]any, err error) {
 }
 
 // Unmarshal unmarshals the response body into the provided value `v`,
-// which should be a pointer.
+// which should be a pointer. If an error result type was registered and the
+// response indicates failure, v is left untouched and an *HTTPError is returned.
 func (r *Response) Unmarshal(v any) error {
-	if r.err != nil {
-		return r.err
+	if err := r.load(); err != nil {
+		return err
+	}
+	if r.failed {
+		return newHTTPError(r)
 	}
 	if len(r.bodyBytes) == 0 {
 		return nil
@@ -119,8 +330,11 @@ func (r *Response) CookiesMap() map[string]string {
 func ToStruct[T any](r *Response) (T, error) {
 	var result T
 
-	if r.err != nil {
-		return result, r.err
+	if err := r.load(); err != nil {
+		return result, err
+	}
+	if r.failed {
+		return result, newHTTPError(r)
 	}
 	if len(r.bodyBytes) == 0 {
 		return result, nil
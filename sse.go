@@ -0,0 +1,401 @@
+package requesto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotEventStream is returned when a server answers an SSE request with a
+// Content-Type other than "text/event-stream".
+var ErrNotEventStream = errors.New("requesto: response is not a text/event-stream")
+
+// errNoContent is returned by connect when the server answers 204 No Content,
+// which asks the client to stop reconnecting.
+var errNoContent = errors.New("requesto: SSE server responded with 204 No Content")
+
+// Event represents a single message received from a Server-Sent Events stream.
+type Event struct {
+	// ID is the last event ID seen on the stream when this event was dispatched.
+	ID string
+	// Event is the event type. It defaults to "message".
+	Event string
+	// Data is the event payload. Multiple data lines are joined with "\n".
+	Data string
+	// Retry is the reconnection delay sent with this event, if any.
+	Retry time.Duration
+}
+
+// sseConfig holds the configuration for an EventStream.
+type sseConfig struct {
+	reconnect     bool
+	maxReconnects int
+	retry         time.Duration
+	lastEventID   string
+}
+
+// SSEOption is a function that configures an EventStream.
+type SSEOption func(*sseConfig)
+
+// WithSSEReconnect enables automatic reconnection when the stream ends or a
+// network error occurs. maxReconnects limits the number of consecutive failed
+// attempts; a negative value retries until the context is canceled.
+func WithSSEReconnect(maxReconnects int) SSEOption {
+	return func(c *sseConfig) {
+		c.reconnect = true
+		c.maxReconnects = maxReconnects
+	}
+}
+
+// WithSSERetry sets the initial delay before reconnecting. The server may
+// override it with a "retry" field. The default is 3 seconds.
+func WithSSERetry(d time.Duration) SSEOption {
+	return func(c *sseConfig) {
+		if d > 0 {
+			c.retry = d
+		}
+	}
+}
+
+// WithSSELastEventID sets the Last-Event-ID sent with the first connection,
+// allowing a previously interrupted stream to be resumed.
+func WithSSELastEventID(id string) SSEOption {
+	return func(c *sseConfig) {
+		c.lastEventID = id
+	}
+}
+
+// EventStream is a live connection to a Server-Sent Events endpoint.
+// Events are delivered through Events or All until the stream ends, the
+// request context is canceled, or Close is called.
+type EventStream struct {
+	req    *Request
+	config *sseConfig
+	cancel context.CancelFunc
+	events chan Event
+
+	mu          sync.Mutex
+	resp        *Response
+	lastEventID string
+	retry       time.Duration
+	err         error
+	closed      bool
+}
+
+// SSE sends the request and opens a Server-Sent Events stream on the response.
+// The request defaults to GET, is sent in streaming mode and passes through the
+// client middleware chain on every (re)connection. An error is returned if the
+// first connection fails or the server does not answer with an event stream.
+// The client and request timeouts only cover each connection up to its
+// response headers; use SetIdleReadTimeout to detect a stalled stream.
+func (r *Request) SSE(opts ...SSEOption) (*EventStream, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	config := &sseConfig{
+		retry: 3 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.ctx = ctx
+	r.stream = true
+	if r.method == "" {
+		r.method = http.MethodGet
+	}
+	r.headers.Set("Accept", "text/event-stream")
+	r.headers.Set("Cache-Control", "no-cache")
+
+	s := &EventStream{
+		req:         r,
+		config:      config,
+		cancel:      cancel,
+		events:      make(chan Event),
+		lastEventID: config.lastEventID,
+		retry:       config.retry,
+	}
+
+	resp, _, err := s.connect()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go s.run(resp)
+	return s, nil
+}
+
+// Events returns the channel on which events are delivered. The channel is
+// closed when the stream terminates; Err reports why.
+func (s *EventStream) Events() <-chan Event {
+	return s.events
+}
+
+// All returns an iterator over the events of the stream.
+func (s *EventStream) All() iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		for event := range s.events {
+			if !yield(event) {
+				s.Close()
+				return
+			}
+		}
+	}
+}
+
+// Response returns the response of the current connection.
+func (s *EventStream) Response() *Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resp
+}
+
+// LastEventID returns the ID of the last event received on the stream.
+func (s *EventStream) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventID
+}
+
+// Err returns the error that terminated the stream, if any. It returns nil
+// while the stream is running and after Close has been called.
+func (s *EventStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close terminates the stream and releases the underlying connection.
+func (s *EventStream) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cancel()
+	return nil
+}
+
+// connect sends the request and validates that the response is an event
+// stream. retry reports whether a failed connection may be retried: only
+// network errors are, while HTTP errors and unexpected content end the stream.
+func (s *EventStream) connect() (resp *Response, retry bool, err error) {
+	if id := s.LastEventID(); id != "" {
+		s.req.headers.Set("Last-Event-ID", id)
+	}
+
+	resp, err = s.req.send()
+	if err != nil {
+		if resp != nil {
+			resp.Close()
+			return nil, false, err
+		}
+		return nil, true, err
+	}
+
+	if resp.Resp.StatusCode != http.StatusOK {
+		resp.Close()
+		if resp.Resp.StatusCode == http.StatusNoContent {
+			return nil, false, errNoContent
+		}
+		return nil, false, fmt.Errorf("requesto: unexpected SSE response status: %s", resp.Resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		resp.Close()
+		return nil, false, fmt.Errorf("%w: got %q", ErrNotEventStream, resp.Resp.Header.Get("Content-Type"))
+	}
+
+	s.mu.Lock()
+	s.resp = resp
+	s.mu.Unlock()
+	return resp, false, nil
+}
+
+// run reads events from the current connection and reconnects as configured
+// until the stream terminates. A 204 No Content response tells the client to
+// stop reconnecting and ends the stream without an error.
+func (s *EventStream) run(resp *Response) {
+	defer close(s.events)
+	defer s.cancel()
+
+	ctx := s.req.ctx
+	failures := 0
+	for {
+		err := s.consume(resp)
+		resp.Close()
+
+		if ctx.Err() != nil {
+			s.finish(ctx.Err())
+			return
+		}
+		if !s.config.reconnect {
+			s.finish(err)
+			return
+		}
+
+		// Keep trying to reconnect until it succeeds or the attempts run out.
+		for {
+			if s.config.maxReconnects >= 0 && failures >= s.config.maxReconnects {
+				s.finish(err)
+				return
+			}
+			failures++
+
+			s.mu.Lock()
+			delay := s.retry
+			s.mu.Unlock()
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				s.finish(ctx.Err())
+				return
+			case <-timer.C:
+			}
+
+			var retry bool
+			resp, retry, err = s.connect()
+			if err == nil {
+				failures = 0
+				break
+			}
+			if ctx.Err() != nil {
+				s.finish(ctx.Err())
+				return
+			}
+			if !retry {
+				s.finish(err)
+				return
+			}
+		}
+	}
+}
+
+// finish records the terminating error unless the stream was closed by the caller.
+func (s *EventStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || errors.Is(err, io.EOF) || errors.Is(err, errNoContent) {
+		return
+	}
+	s.err = err
+}
+
+// consume parses events from resp until its body ends. It returns io.EOF when
+// the server closed the stream cleanly.
+func (s *EventStream) consume(resp *Response) error {
+	scanner := bufio.NewScanner(resp.Body())
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	scanner.Split(scanSSELines)
+
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+		retry     time.Duration
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// An empty line dispatches the buffered event.
+		if line == "" {
+			if hasData {
+				event := Event{
+					ID:    s.LastEventID(),
+					Event: eventType,
+					Data:  data.String(),
+					Retry: retry,
+				}
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				select {
+				case s.events <- event:
+				case <-s.req.ctx.Done():
+					return s.req.ctx.Err()
+				}
+			}
+			eventType, hasData, retry = "", false, 0
+			data.Reset()
+			continue
+		}
+
+		// Lines starting with a colon are comments.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.mu.Lock()
+				s.lastEventID = value
+				s.mu.Unlock()
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				s.mu.Lock()
+				s.retry = retry
+				s.mu.Unlock()
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// scanSSELines is a bufio.SplitFunc that splits on "\r\n", "\n" or "\r",
+// the three line terminators allowed by the event stream format.
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
+				}
+				return i + 1, data[:i], nil
+			}
+			if !atEOF {
+				// Need more data to know whether "\r" is followed by "\n".
+				return 0, nil, nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
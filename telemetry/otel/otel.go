@@ -0,0 +1,136 @@
+// Package otel adapts OpenTelemetry tracers and meters to the requesto
+// Tracer and Meter interfaces.
+//
+// It is a separate module so that the core requesto module stays free of
+// dependencies:
+//
+//	client := requesto.NewClient("https://api.example.com",
+//		requesto.WithTracer(otel.NewTracer(tracerProvider.Tracer(otel.ScopeName))),
+//		requesto.WithMeter(otel.NewMeter(meterProvider.Meter(otel.ScopeName))),
+//	)
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// ScopeName is the suggested instrumentation scope name for the tracer and meter.
+const ScopeName = "github.com/Kaguya233qwq/requesto"
+
+// NewTracer returns a requesto.Tracer that creates client spans with t.
+// Spans become children of the OpenTelemetry span found in the request context.
+func NewTracer(t trace.Tracer) requesto.Tracer {
+	return &tracer{tracer: t}
+}
+
+// NewMeter returns a requesto.Meter that records every measurement name into
+// a Float64Histogram of m, created on first use. Durations are recorded in
+// seconds and sizes in bytes.
+func NewMeter(m metric.Meter) requesto.Meter {
+	return &meter{meter: m, histograms: make(map[string]metric.Float64Histogram)}
+}
+
+// tracer implements requesto.Tracer.
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attrs ...requesto.Attribute) (context.Context, requesto.Span) {
+	ctx, s := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(keyValues(attrs)...),
+	)
+	return ctx, &span{span: s}
+}
+
+// span implements requesto.Span.
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SpanContext() requesto.SpanContext {
+	sc := s.span.SpanContext()
+	return requesto.SpanContext{
+		TraceID: [16]byte(sc.TraceID()),
+		SpanID:  [8]byte(sc.SpanID()),
+		Sampled: sc.IsSampled(),
+	}
+}
+
+func (s *span) SetAttributes(attrs ...requesto.Attribute) {
+	s.span.SetAttributes(keyValues(attrs)...)
+}
+
+func (s *span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *span) End() {
+	s.span.End()
+}
+
+// meter implements requesto.Meter.
+type meter struct {
+	meter      metric.Meter
+	mu         sync.Mutex
+	histograms map[string]metric.Float64Histogram
+}
+
+func (m *meter) Record(ctx context.Context, name string, value float64, attrs ...requesto.Attribute) {
+	h, err := m.histogram(name)
+	if err != nil {
+		return
+	}
+	h.Record(ctx, value, metric.WithAttributes(keyValues(attrs)...))
+}
+
+// histogram returns the histogram for name, creating it if needed.
+func (m *meter) histogram(name string) (metric.Float64Histogram, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.histograms[name]; ok {
+		return h, nil
+	}
+	unit := "By"
+	if name == requesto.MetricRequestDuration {
+		unit = "s"
+	}
+	h, err := m.meter.Float64Histogram(name, metric.WithUnit(unit))
+	if err != nil {
+		return nil, err
+	}
+	m.histograms[name] = h
+	return h, nil
+}
+
+// keyValues converts requesto attributes to OpenTelemetry attributes.
+func keyValues(attrs []requesto.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(attr.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(attr.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(attr.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(attr.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(attr.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
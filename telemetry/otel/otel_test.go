@@ -0,0 +1,88 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestAdapter(t *testing.T) {
+	var traceparent string
+	transport := mock.NewTransport()
+	transport.On("GET", "/traced").Match(func(req *http.Request) bool {
+		traceparent = req.Header.Get("Traceparent")
+		return true
+	}).Reply(503, "busy")
+
+	spans := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client := requesto.NewClient("https://api.example.com",
+		requesto.WithTransport(transport),
+		requesto.WithRaiseForStatus(true),
+		requesto.WithTracer(NewTracer(tracerProvider.Tracer(ScopeName))),
+		requesto.WithMeter(NewMeter(meterProvider.Meter(ScopeName))),
+	)
+	if _, err := client.NewRequest().JoinPath("/traced").Get(); err == nil {
+		t.Fatal("expected the 503 to be raised")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected a request and an attempt span, got %d", len(ended))
+	}
+	attempt, request := ended[0], ended[1]
+	if attempt.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("expected the attempt span to be a child of the request span")
+	}
+	sc := requesto.SpanContext{
+		TraceID: [16]byte(attempt.SpanContext().TraceID()),
+		SpanID:  [8]byte(attempt.SpanContext().SpanID()),
+		Sampled: true,
+	}
+	if want := sc.TraceParent(); traceparent != want {
+		t.Errorf("Traceparent = %q, want %q", traceparent, want)
+	}
+	if request.Status().Code != codes.Error {
+		t.Errorf("expected the request span to record the error, got %v", request.Status())
+	}
+	if !hasAttribute(attempt.Attributes(), attribute.Int("http.response.status_code", 503)) {
+		t.Errorf("missing status code in %v", attempt.Attributes())
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("collecting metrics failed: %v", err)
+	}
+	units := map[string]string{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			units[m.Name] = m.Unit
+		}
+	}
+	if units[requesto.MetricRequestDuration] != "s" || units[requesto.MetricResponseBodySize] != "By" {
+		t.Errorf("unexpected metrics %v", units)
+	}
+}
+
+// hasAttribute reports whether attrs contains kv.
+func hasAttribute(attrs []attribute.KeyValue, kv attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
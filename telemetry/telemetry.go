@@ -0,0 +1,177 @@
+// Package telemetry provides an in-memory implementation of the requesto
+// Tracer and Meter interfaces, useful for tests.
+//
+// The OpenTelemetry adapter lives in the separate module
+// github.com/Kaguya233qwq/requesto/telemetry/otel, so that the core module
+// stays free of dependencies.
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// spanKey is the context key under which the active span is stored.
+type spanKey struct{}
+
+// SpanData is a finished span recorded by an Exporter.
+type SpanData struct {
+	Name       string
+	Context    requesto.SpanContext
+	Parent     requesto.SpanContext
+	Attributes map[string]any
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+}
+
+// Measurement is a single value recorded by an Exporter's Meter.
+type Measurement struct {
+	Name       string
+	Value      float64
+	Attributes map[string]any
+}
+
+// Exporter collects spans and measurements in memory.
+type Exporter struct {
+	mu           sync.Mutex
+	spans        []SpanData
+	measurements []Measurement
+}
+
+// NewExporter creates an empty Exporter.
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+// Tracer returns a requesto.Tracer that records finished spans in the exporter.
+func (e *Exporter) Tracer() requesto.Tracer {
+	return &tracer{exporter: e}
+}
+
+// Meter returns a requesto.Meter that records measurements in the exporter.
+func (e *Exporter) Meter() requesto.Meter {
+	return &meter{exporter: e}
+}
+
+// Spans returns the finished spans in the order they ended.
+func (e *Exporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Measurements returns the recorded measurements in order.
+func (e *Exporter) Measurements() []Measurement {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Measurement(nil), e.measurements...)
+}
+
+// Reset discards everything recorded so far.
+func (e *Exporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+	e.measurements = nil
+}
+
+// ContextWithSpanContext returns a context whose spans become children of sc,
+// for example one parsed from an incoming traceparent header.
+func ContextWithSpanContext(ctx context.Context, sc requesto.SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// tracer implements requesto.Tracer.
+type tracer struct {
+	exporter *Exporter
+}
+
+// Start creates a span that is a child of the span stored in ctx, if any.
+func (t *tracer) Start(ctx context.Context, name string, attrs ...requesto.Attribute) (context.Context, requesto.Span) {
+	parent, _ := ctx.Value(spanKey{}).(requesto.SpanContext)
+
+	sc := requesto.SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if !parent.IsValid() {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	s := &span{
+		exporter: t.exporter,
+		data: SpanData{
+			Name:       name,
+			Context:    sc,
+			Parent:     parent,
+			Attributes: make(map[string]any),
+			Start:      time.Now(),
+		},
+	}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, sc), s
+}
+
+// span implements requesto.Span.
+type span struct {
+	exporter *Exporter
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+}
+
+func (s *span) SpanContext() requesto.SpanContext {
+	return s.data.Context
+}
+
+func (s *span) SetAttributes(attrs ...requesto.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		s.data.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.exporter.mu.Lock()
+	s.exporter.spans = append(s.exporter.spans, data)
+	s.exporter.mu.Unlock()
+}
+
+// meter implements requesto.Meter.
+type meter struct {
+	exporter *Exporter
+}
+
+func (m *meter) Record(ctx context.Context, name string, value float64, attrs ...requesto.Attribute) {
+	measurement := Measurement{
+		Name:       name,
+		Value:      value,
+		Attributes: make(map[string]any, len(attrs)),
+	}
+	for _, attr := range attrs {
+		measurement.Attributes[attr.Key] = attr.Value
+	}
+	m.exporter.mu.Lock()
+	m.exporter.measurements = append(m.exporter.measurements, measurement)
+	m.exporter.mu.Unlock()
+}
@@ -0,0 +1,120 @@
+package testing
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestAuthHelpers(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/basic").WithHeader("Authorization", "Basic dXNlcjpwYXNz").WithHeader("X-Trace", "1").Reply(200, "")
+	transport.On("GET", "/bearer").WithHeader("Authorization", "Bearer tok").Reply(200, "")
+	transport.On("GET", "/key").WithQuery("api_key", "k").WithHeader("X-Api-Key", "h").Reply(200, "")
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.SetHeader("X-Trace", "1")
+	client.SetBasicAuth("user", "pass")
+	client.SetAPIKey("X-Api-Key", "h", requesto.APIKeyInHeader)
+
+	if _, err := client.NewRequest().JoinPath("/basic").Get(); err != nil {
+		t.Errorf("basic auth: %v", err)
+	}
+	if _, err := client.NewRequest().JoinPath("/bearer").SetBearerToken("tok").Get(); err != nil {
+		t.Errorf("bearer token: %v", err)
+	}
+	if _, err := client.NewRequest().JoinPath("/key").SetAPIKey("api_key", "k", requesto.APIKeyInQuery).Get(); err != nil {
+		t.Errorf("api key: %v", err)
+	}
+	transport.AssertExpectations(t)
+}
+
+func TestDigestAuth(t *testing.T) {
+	const realm, nonce, username, password = "test", "abc123", "alice", "secret"
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	challenges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			challenges++
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm=%q, qop="auth,auth-int", nonce=%q, opaque="xyz"`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		params := map[string]string{}
+		for part := range strings.SplitSeq(strings.TrimPrefix(auth, "Digest "), ", ") {
+			key, value, _ := strings.Cut(part, "=")
+			params[key] = strings.Trim(value, `"`)
+		}
+		ha1 := md5hex(username + ":" + realm + ":" + password)
+		ha2 := md5hex(r.Method + ":" + params["uri"])
+		expected := md5hex(strings.Join([]string{ha1, nonce, params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if params["response"] != expected || params["uri"] != r.URL.RequestURI() || params["opaque"] != "xyz" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "welcome ", params["nc"])
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewDigestAuth(username, password))
+
+	for _, expected := range []string{"welcome 00000001", "welcome 00000002"} {
+		resp, err := client.NewRequest().JoinPath("/private").SetParams(map[string]string{"q": "1"}).Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if text, _ := resp.Text(); text != expected {
+			t.Errorf("expected %q, got %q (status %d)", expected, text, resp.StatusCode())
+		}
+	}
+	if challenges != 1 {
+		t.Errorf("expected a single challenge round-trip, got %d", challenges)
+	}
+}
+
+func TestDigestAuth_FallbackAndQuoting(t *testing.T) {
+	const username, password = `jürgen "j\`, "secret"
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			// The first challenge uses an unsupported algorithm.
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="n1", algorithm=SHA-512-256, qop="auth", Digest realm="test", nonce="n2", algorithm=MD5, qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		authorizations = append(authorizations, auth)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewDigestAuth(username, password))
+
+	resp, err := client.Get()
+	if err != nil || resp.StatusCode() != 200 {
+		t.Fatalf("expected the MD5 challenge to be answered, got status %d, %v", resp.StatusCode(), err)
+	}
+	if len(authorizations) != 1 {
+		t.Fatalf("expected one authorized request, got %d", len(authorizations))
+	}
+	auth := authorizations[0]
+	for _, expected := range []string{`username="jürgen \"j\\"`, `nonce="n2"`, "algorithm=MD5"} {
+		if !strings.Contains(auth, expected) {
+			t.Errorf("expected %s in %s", expected, auth)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package testing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+)
+
+func TestCache_FreshAndRevalidated(t *testing.T) {
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+			fmt.Fprintf(w, "fresh %d", hits[r.URL.Path])
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			fmt.Fprint(w, "tagged")
+		}
+	}))
+	defer server.Close()
+
+	storage := middleware.NewMemoryCache(10)
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewCache(middleware.CachePolicy{Storage: storage}))
+
+	for range 3 {
+		resp, err := client.NewRequest().JoinPath("/fresh").Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if text, _ := resp.Text(); text != "fresh 1" {
+			t.Errorf("expected the cached body, got %q", text)
+		}
+	}
+	if hits["/fresh"] != 1 {
+		t.Errorf("expected a single request to /fresh, got %d", hits["/fresh"])
+	}
+
+	for range 2 {
+		resp, err := client.NewRequest().JoinPath("/etag").Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode() != 200 {
+			t.Errorf("expected a 304 to be served as 200, got %d", resp.StatusCode())
+		}
+		if text, _ := resp.Text(); text != "tagged" {
+			t.Errorf("expected the revalidated body, got %q", text)
+		}
+	}
+	if hits["/etag"] != 2 {
+		t.Errorf("expected /etag to be revalidated, got %d requests", hits["/etag"])
+	}
+
+	// An unsafe request invalidates the cached entry.
+	client.NewRequest().JoinPath("/fresh").Post()
+	client.NewRequest().JoinPath("/fresh").Get()
+	if hits["/fresh"] != 3 {
+		t.Errorf("expected POST to invalidate the entry, got %d requests", hits["/fresh"])
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(w, "v%d", n)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewCache(middleware.CachePolicy{}))
+
+	if text := cachedText(t, client, nil); text != "v1" {
+		t.Fatalf("first request returned %q", text)
+	}
+	// The stale entry is served at once and refreshed in the background.
+	if text := cachedText(t, client, nil); text != "v1" {
+		t.Errorf("expected the stale body, got %q", text)
+	}
+	deadline := time.Now().Add(time.Second)
+	text := ""
+	for text != "v2" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		text = cachedText(t, client, nil)
+	}
+	if text != "v2" {
+		t.Errorf("expected the background revalidation to refresh the entry, got %q", text)
+	}
+}
+
+func TestCache_VaryVariants(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	for _, storage := range []middleware.CacheStorage{middleware.NewMemoryCache(10), newDiskCache(t)} {
+		hits = 0
+		client := requesto.NewClient(server.URL)
+		client.Use(middleware.NewCache(middleware.CachePolicy{Storage: storage}))
+
+		for range 2 {
+			for _, lang := range []string{"en", "fr", "de"} {
+				if text := cachedText(t, client, map[string]string{"Accept-Language": lang}); text != lang {
+					t.Errorf("%T: expected the %s variant, got %q", storage, lang, text)
+				}
+			}
+		}
+		if hits != 3 {
+			t.Errorf("%T: expected one request per variant, got %d", storage, hits)
+		}
+	}
+}
+
+func TestCache_ExpiresAndHeuristicFreshness(t *testing.T) {
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		now := time.Now()
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		switch r.URL.Path {
+		case "/expires":
+			w.Header().Set("Expires", now.Add(time.Hour).UTC().Format(http.TimeFormat))
+		case "/expired":
+			w.Header().Set("Expires", now.Add(-time.Hour).UTC().Format(http.TimeFormat))
+		case "/heuristic":
+			w.Header().Set("Last-Modified", now.Add(-10*24*time.Hour).UTC().Format(http.TimeFormat))
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewCache(middleware.CachePolicy{}))
+
+	for range 3 {
+		for _, path := range []string{"/expires", "/expired", "/heuristic"} {
+			resp, err := client.NewRequest().JoinPath(path).Get()
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if text, _ := resp.Text(); text != path {
+				t.Errorf("expected body %q, got %q", path, text)
+			}
+		}
+	}
+
+	want := map[string]int{"/expires": 1, "/expired": 3, "/heuristic": 1}
+	for path, n := range want {
+		if hits[path] != n {
+			t.Errorf("%s: expected %d requests, got %d", path, n, hits[path])
+		}
+	}
+}
+
+func TestCache_DiskCachePersists(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(w, "disk %d", hits)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for range 2 {
+		// Each client opens the directory afresh, as a new process would.
+		storage, err := middleware.NewDiskCache(dir)
+		if err != nil {
+			t.Fatalf("NewDiskCache failed: %v", err)
+		}
+		client := requesto.NewClient(server.URL)
+		client.Use(middleware.NewCache(middleware.CachePolicy{Storage: storage}))
+		if text := cachedText(t, client, nil); text != "disk 1" {
+			t.Errorf("expected the body stored on disk, got %q", text)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("expected a single request, got %d", hits)
+	}
+
+	storage, _ := middleware.NewDiskCache(dir)
+	storage.Delete(server.URL)
+	if _, ok := storage.Get(server.URL); ok {
+		t.Error("expected Delete to remove the entry")
+	}
+}
+
+// cachedText sends a GET request with the given headers and returns the body.
+func cachedText(t *testing.T, client *requesto.Client, headers map[string]string) string {
+	t.Helper()
+	resp, err := client.NewRequest().SetHeaders(headers).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	text, err := resp.Text()
+	if err != nil {
+		t.Fatalf("reading body failed: %v", err)
+	}
+	return text
+}
+
+// newDiskCache creates a DiskCache in a temporary directory.
+func newDiskCache(t *testing.T) *middleware.DiskCache {
+	t.Helper()
+	storage, err := middleware.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskCache failed: %v", err)
+	}
+	return storage
+}
+
+func TestCache_ConcurrentVariantsAndRequest(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewCache(middleware.CachePolicy{}))
+
+	langs := []string{"en", "fr", "de", "it", "es", "pt", "nl", "pl"}
+	var wg sync.WaitGroup
+	for _, lang := range langs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.NewRequest().SetHeader("Accept-Language", lang).Get()
+		}()
+	}
+	wg.Wait()
+
+	// Every variant survived the concurrent stores.
+	for _, lang := range langs {
+		resp, err := client.NewRequest().SetHeader("Accept-Language", lang).Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if text, _ := resp.Text(); text != lang {
+			t.Errorf("expected the %s variant, got %q", lang, text)
+		}
+		if resp.Resp.Request == nil || resp.Resp.Request.URL.String() != server.URL {
+			t.Errorf("expected the cached response to carry its request, got %+v", resp.Resp.Request)
+		}
+	}
+	if got := hits.Load(); got != int32(len(langs)) {
+		t.Errorf("expected one request per variant, got %d", got)
+	}
+}
+
+func TestCache_RevalidationReleasesStreams(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		}
+		w.Write(make([]byte, 1<<20))
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	client.Use(middleware.NewCache(middleware.CachePolicy{}))
+	if _, err := client.Get(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// The background revalidation gets an unread stream that is not stored.
+	if _, err := client.NewRequest().SetStream(true).Get(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for (hits.Load() < 2 || client.Stats().ActiveConns != 0) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := client.Stats(); hits.Load() != 2 || stats.ActiveConns != 0 {
+		t.Errorf("expected the revalidation response to be released, got %d requests and %+v", hits.Load(), stats)
+	}
+}
@@ -0,0 +1,91 @@
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/cassette"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintf(w, "hello %s", r.URL.Query().Get("name"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "hello.json")
+
+	rec, err := cassette.New(path, cassette.WithMode(cassette.ModeRecord))
+	if err != nil {
+		t.Fatalf("cannot create recorder: %v", err)
+	}
+	client := requesto.NewClient(server.URL, requesto.WithTransport(rec))
+	client.Headers.Set("Authorization", "Bearer secret")
+	if _, err := client.NewRequest().SetParams(map[string]string{"name": "alice"}).Get(); err != nil {
+		t.Fatalf("recording request failed: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("cannot save cassette: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cassette was not written: %v", err)
+	}
+	if strings.Contains(string(data), "Bearer secret") {
+		t.Error("expected the Authorization header to be redacted")
+	}
+
+	replay, err := cassette.New(path)
+	if err != nil {
+		t.Fatalf("cannot load cassette: %v", err)
+	}
+	client = requesto.NewClient(server.URL, requesto.WithTransport(replay))
+	resp, err := client.NewRequest().SetParams(map[string]string{"name": "alice"}).Get()
+	if err != nil {
+		t.Fatalf("replayed request failed: %v", err)
+	}
+	if text, _ := resp.Text(); text != "hello alice" {
+		t.Errorf("unexpected replayed body %q", text)
+	}
+	if hits != 1 {
+		t.Errorf("expected the server to be hit once, got %d", hits)
+	}
+
+	_, err = client.NewRequest().SetParams(map[string]string{"name": "bob"}).Get()
+	if !errors.Is(err, cassette.ErrInteractionNotFound) {
+		t.Errorf("expected ErrInteractionNotFound, got %v", err)
+	}
+}
+
+func TestCassette_RecordReplacesStaleCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.json")
+	if err := os.WriteFile(path, []byte(`{"interactions":[{"request":{"method":"GET","url":"http://old"},"response":{"status_code":200}}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := cassette.New(path, cassette.WithMode(cassette.ModeRecord))
+	if err != nil {
+		t.Fatalf("cannot create recorder: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("cannot save cassette: %v", err)
+	}
+
+	replay, err := cassette.New(path)
+	if err != nil {
+		t.Fatalf("cannot load cassette: %v", err)
+	}
+	if n := len(replay.Interactions()); n != 0 {
+		t.Errorf("expected the stale interactions to be dropped, got %d", n)
+	}
+}
@@ -0,0 +1,173 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+)
+
+// newUnixServer serves handler on a Unix socket in a temporary directory and
+// returns the socket path.
+func newUnixServer(t *testing.T, handler http.Handler) string {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return socket
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := newUnixServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	}))
+
+	client := requesto.NewClient("http://docker", requesto.WithUnixSocket(socket))
+	resp, err := client.NewRequest().JoinPath("/v1.41/info").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "docker /v1.41/info" {
+		t.Errorf("body = %q", got)
+	}
+
+	for _, base := range []string{"unix://" + socket, "unix://" + socket + "/", "http+unix://" + url.PathEscape(socket)} {
+		client := requesto.NewClient(base)
+		resp, err := client.NewRequest().JoinPath("/containers/json").SetParams(map[string]string{"all": "1"}).Get()
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", base, err)
+		}
+		if got := text(t, resp); got != "localhost /containers/json?all=1" {
+			t.Errorf("%s: body = %q", base, got)
+		}
+	}
+
+	resp, err = requesto.NewClient("unix://" + socket).NewRequest().Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "localhost /" {
+		t.Errorf("body = %q", got)
+	}
+
+	target := "http+unix://" + url.PathEscape(socket) + "/v1.41/info"
+	resp, err = requesto.NewClient("http://docker").NewRequest().SetURL(target).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "localhost /v1.41/info" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestUnixSocketPathWithColon(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run:1")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	resp, err := requesto.NewClient("unix://" + socket).NewRequest().JoinPath("/info").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "/info" {
+		t.Errorf("path = %q", got)
+	}
+}
+
+func TestUnixSocketKeepsRequestURL(t *testing.T) {
+	socket := newUnixServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+			return
+		}
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.URL.Query().Get("sig")))
+	}))
+
+	client := requesto.NewClient("unix://"+socket, requesto.WithRaiseForStatus(true))
+	client.Use(middleware.NewSigner(middleware.SignerFunc(func(sr *middleware.SigningRequest) error {
+		query := sr.URL.Query()
+		query.Set("sig", "signed")
+		sr.URL.RawQuery = query.Encode()
+		return nil
+	})))
+
+	// The error names the request URL, not the dial key of the socket.
+	resp, err := client.NewRequest().JoinPath("/private").Get()
+	var httpErr *requesto.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if httpErr.URL != "http://localhost/private?sig=REDACTED" || strings.Contains(err.Error(), "unix-") {
+		t.Errorf("unexpected error URL %q in %v", httpErr.URL, err)
+	}
+	if host := resp.Resp.Request.URL.Host; host != "localhost" {
+		t.Errorf("response request host = %q", host)
+	}
+
+	// Cookies are stored for the request URL, and the signed request still
+	// goes to the socket.
+	if _, err := client.NewRequest().JoinPath("/login").Get(); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if cookies := client.CookieJar.Cookies(&url.URL{Scheme: "http", Host: "localhost"}); len(cookies) != 1 {
+		t.Errorf("expected the session cookie for localhost, got %v", cookies)
+	}
+	resp, err = client.NewRequest().JoinPath("/private").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "signed" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var dials atomic.Int32
+	var d net.Dialer
+	client := requesto.NewClient("http://service.internal", requesto.WithDialContext(
+		func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			return d.DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	))
+	for range 2 {
+		if _, err := client.NewRequest().Get(); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+	if got := dials.Load(); got != 1 {
+		t.Errorf("dials = %d, want 1 (connection reused)", got)
+	}
+}
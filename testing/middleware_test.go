@@ -0,0 +1,426 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestRetrier_RetryAfterAndHook(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/limited").Reply(429, "slow down").ReplyHeader("Retry-After", "0").Once()
+	transport.On("GET", "/limited").Reply(200, "ok").Once()
+
+	var delays []time.Duration
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		Backoff: middleware.ExponentialBackoff(time.Hour),
+		RetryIf: func(resp *requesto.Response, err error) bool {
+			return err != nil || resp.StatusCode() == 429
+		},
+		OnRetry: func(attempt int, delay time.Duration, resp *requesto.Response, err error) {
+			delays = append(delays, delay)
+		},
+	}))
+
+	resp, err := client.NewRequest().JoinPath("/limited").Get()
+	if err != nil || resp.StatusCode() != 200 {
+		t.Fatalf("expected success after retry, got %v", err)
+	}
+	if len(delays) != 1 || delays[0] != 0 {
+		t.Errorf("expected Retry-After to override the backoff, got %v", delays)
+	}
+	transport.AssertExpectations(t)
+}
+
+func TestRetrier_StopsOnContextCancel(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/down").ReplyError(errors.New("connection refused"))
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		RetryCount: 5,
+		Backoff:    middleware.ConstantBackoff(time.Hour),
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.NewRequestWithContext(ctx).JoinPath("/down").Get()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retrier did not abort promptly, took %v", elapsed)
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	if d := middleware.LinearBackoff(time.Second)(3, 0); d != 3*time.Second {
+		t.Errorf("linear: expected 3s, got %v", d)
+	}
+	if d := middleware.ExponentialBackoff(time.Second)(4, 0); d != 8*time.Second {
+		t.Errorf("exponential: expected 8s, got %v", d)
+	}
+	for range 100 {
+		if d := middleware.DecorrelatedJitterBackoff(time.Second, 5*time.Second)(3, 4*time.Second); d < time.Second || d > 5*time.Second {
+			t.Fatalf("decorrelated jitter out of range: %v", d)
+		}
+	}
+	if d := middleware.DecorrelatedJitterBackoff(time.Second, 0)(1, 0); d < time.Second {
+		t.Errorf("decorrelated jitter without cap: expected at least 1s, got %v", d)
+	}
+
+	// Delays saturate instead of overflowing on long retry sequences.
+	jitter := middleware.ExponentialJitterBackoff(time.Second)
+	decorrelated := middleware.DecorrelatedJitterBackoff(time.Second, 0)
+	var prev time.Duration
+	for attempt := 1; attempt < 100; attempt++ {
+		if d := jitter(attempt, 0); d < 0 {
+			t.Fatalf("exponential jitter: negative delay %v at attempt %d", d, attempt)
+		}
+		if prev = decorrelated(attempt, prev); prev < time.Second {
+			t.Fatalf("decorrelated jitter: delay %v below base at attempt %d", prev, attempt)
+		}
+	}
+	if d := middleware.LinearBackoff(time.Hour)(1<<40, 0); d <= 0 {
+		t.Errorf("linear: expected a saturated delay, got %v", d)
+	}
+}
+
+func TestRetrier_ReplaysFileUploads(t *testing.T) {
+	var uploads []string
+	transport := mock.NewTransport()
+	transport.On("POST", "/upload").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		data, _ := io.ReadAll(file)
+		uploads = append(uploads, string(data))
+		status := 500
+		if len(uploads) == 2 {
+			status = 200
+		}
+		return mock.NewResponse(req, status, nil, nil), nil
+	})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		RetryBackoff: time.Millisecond,
+		RetryIf: func(resp *requesto.Response, err error) bool {
+			return err != nil || resp.StatusCode() >= 500
+		},
+	}))
+
+	resp, err := client.NewRequest().JoinPath("/upload").
+		SetFiles(map[string]requesto.File{"file": requesto.FileFromBytes("a.txt", []byte("hello"))}).
+		Post()
+	if err != nil || resp.StatusCode() != 200 {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if len(uploads) != 2 || uploads[0] != "hello" || uploads[1] != "hello" {
+		t.Errorf("expected the file to be resent intact, got %q", uploads)
+	}
+
+	_, err = client.NewRequest().JoinPath("/upload").
+		SetFiles(map[string]requesto.File{"file": {Name: "b.txt", Content: io.NopCloser(strings.NewReader("once"))}}).
+		Post()
+	if !errors.Is(err, requesto.ErrBodyNotReplayable) {
+		t.Errorf("expected ErrBodyNotReplayable, got %v", err)
+	}
+}
+
+func TestRetrier_ReplaysPositionedFiles(t *testing.T) {
+	var uploads []string
+	transport := mock.NewTransport()
+	transport.On("POST", "/upload").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		data, _ := io.ReadAll(file)
+		uploads = append(uploads, string(data))
+		status := 500
+		if len(uploads) == 2 {
+			status = 200
+		}
+		return mock.NewResponse(req, status, nil, nil), nil
+	})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		RetryBackoff: time.Millisecond,
+		RetryIf: func(resp *requesto.Response, err error) bool {
+			return err != nil || resp.StatusCode() >= 500
+		},
+	}))
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, []byte("header:payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(int64(len("header:")), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.NewRequest().JoinPath("/upload").
+		SetFiles(map[string]requesto.File{"file": {Name: "data.bin", Content: f}}).
+		Post()
+	if err != nil || resp.StatusCode() != 200 {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if len(uploads) != 2 || uploads[0] != "payload" || uploads[1] != "payload" {
+		t.Errorf("expected the upload to start where the file was positioned, got %q", uploads)
+	}
+
+	// The file is closed once the request, including its retries, is done.
+	if _, err := f.Seek(0, io.SeekStart); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected the file to be closed, got %v", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/unstable").Reply(500, "boom").Times(2)
+	transport.On("GET", "/unstable").Reply(200, "ok")
+
+	var changes []string
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewCircuitBreaker(middleware.CircuitBreakerPolicy{
+		FailureThreshold: 2,
+		CoolDown:         20 * time.Millisecond,
+		OnStateChange: func(key string, from, to middleware.CircuitState) {
+			changes = append(changes, key+":"+to.String())
+		},
+	}))
+
+	for range 2 {
+		client.NewRequest().JoinPath("/unstable").Get()
+	}
+
+	_, err := client.NewRequest().JoinPath("/unstable").Get()
+	var openErr *middleware.CircuitOpenError
+	if !errors.Is(err, middleware.ErrCircuitOpen) || !errors.As(err, &openErr) || openErr.Key != "api.example.com" {
+		t.Fatalf("expected the circuit to be open, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	resp, err := client.NewRequest().JoinPath("/unstable").Get()
+	if err != nil || resp.StatusCode() != 200 {
+		t.Fatalf("expected the trial request to succeed, got %v", err)
+	}
+
+	expected := []string{"api.example.com:open", "api.example.com:half-open", "api.example.com:closed"}
+	if strings.Join(changes, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected state changes %v", changes)
+	}
+}
+
+func TestCircuitBreaker_CanceledTrialIsNeutral(t *testing.T) {
+	calls := 0
+	transport := mock.NewTransport()
+	transport.On("GET", "/down").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		calls++
+		if calls == 1 {
+			return mock.NewResponse(req, 500, nil, []byte("boom")), nil
+		}
+		return mock.NewResponse(req, 200, nil, []byte("ok")), nil
+	})
+
+	var changes []string
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewCircuitBreaker(middleware.CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		CoolDown:         10 * time.Millisecond,
+		OnStateChange: func(key string, from, to middleware.CircuitState) {
+			changes = append(changes, to.String())
+		},
+	}))
+
+	client.NewRequest().JoinPath("/down").Get()
+	time.Sleep(20 * time.Millisecond)
+
+	// The canceled trial must neither close the circuit nor reopen it.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.NewRequestWithContext(ctx).JoinPath("/down").Get(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if got := strings.Join(changes, ","); got != "open,half-open" {
+		t.Errorf("state changes after canceled trial = %s", got)
+	}
+
+	if _, err := client.NewRequest().JoinPath("/down").Get(); err != nil {
+		t.Fatalf("trial request failed: %v", err)
+	}
+	if got := strings.Join(changes, ","); got != "open,half-open,closed" {
+		t.Errorf("state changes = %s", got)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "*").Reply(200, "ok")
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewRateLimiter(middleware.RateLimitPolicy{Rate: 50, Burst: 1}))
+
+	start := time.Now()
+	for range 3 {
+		if _, err := client.NewRequest().Get(); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected requests to be spaced out, took only %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.NewRequestWithContext(ctx).Get(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled while waiting, got %v", err)
+	}
+}
+
+func TestLogger_Redaction(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/login").ReplyJSON(200, map[string]any{"user": "alice", "token": "s3cret"})
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewLogger(
+		middleware.WithLogger(logger),
+		middleware.WithHeaders(true),
+		middleware.WithBody(true),
+		middleware.WithRedactedParams("api_key"),
+		middleware.WithRedactedFields("token"),
+	))
+
+	_, err := client.NewRequest().
+		JoinPath("/login").
+		SetParams(map[string]string{"api_key": "k3y"}).
+		SetHeader("Authorization", "Bearer t0ken").
+		Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	record := buf.String()
+	for _, secret := range []string{"s3cret", "k3y", "t0ken"} {
+		if strings.Contains(record, secret) {
+			t.Errorf("expected %q to be redacted in %s", secret, record)
+		}
+	}
+	for _, expected := range []string{`"status":200`, `"method":"GET"`, `"attempt":1`, "alice"} {
+		if !strings.Contains(record, expected) {
+			t.Errorf("expected %s in %s", expected, record)
+		}
+	}
+}
+
+func TestLogger_RequestBody(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("POST", "/login").Reply(200, "password=hunter2").ReplyHeader("Content-Type", "text/plain")
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewLogger(
+		middleware.WithLogger(logger),
+		middleware.WithBody(true),
+		middleware.WithRedactedFields("password"),
+	))
+
+	_, err := client.NewRequest().
+		JoinPath("/login").
+		SetFormData(map[string]string{"user": "alice", "password": "s3cret"}).
+		Post()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	record := buf.String()
+	for _, secret := range []string{"s3cret", "hunter2"} {
+		if strings.Contains(record, secret) {
+			t.Errorf("expected %q to be redacted in %s", secret, record)
+		}
+	}
+	size := len("password=s3cret&user=alice")
+	for _, expected := range []string{`"request_size":` + strconv.Itoa(size), `"request_body":"password=%5BREDACTED%5D&user=alice"`, `"response_body":"[REDACTED]"`} {
+		if !strings.Contains(record, expected) {
+			t.Errorf("expected %s in %s", expected, record)
+		}
+	}
+}
+
+func TestLogger_ErrorsAndUnreadBodies(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("POST", "/fail").Reply(500, `{"token":"s3cret"}`)
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport), requesto.WithRaiseForStatus(true))
+	client.Use(middleware.NewLogger(
+		middleware.WithLogger(logger),
+		middleware.WithRedactedParams("api_key"),
+		middleware.WithRedactedFields("token"),
+	))
+
+	_, err := client.NewRequest().
+		JoinPath("/fail").
+		SetAPIKey("api_key", "k3y", requesto.APIKeyInQuery).
+		SetFiles(map[string]requesto.File{"file": {Name: "a.txt", Content: io.NopCloser(strings.NewReader("once"))}}).
+		Post()
+	var httpErr *requesto.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	record := buf.String()
+	for _, secret := range []string{"s3cret", "k3y"} {
+		if strings.Contains(record, secret) {
+			t.Errorf("expected %q to be redacted in %s", secret, record)
+		}
+	}
+	if !strings.Contains(record, `"error":"requesto: POST https://api.example.com/fail?api_key=%5BREDACTED%5D: 500 Internal Server Error"`) {
+		t.Errorf("unexpected error attribute in %s", record)
+	}
+
+	// With body logging, a body that cannot be built fails the request.
+	buf.Reset()
+	client = requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewLogger(middleware.WithLogger(logger), middleware.WithBody(true)))
+	_, err = client.NewRequest().
+		JoinPath("/fail").
+		SetFiles(map[string]requesto.File{"file": {Name: "missing", Path: filepath.Join(t.TempDir(), "missing")}}).
+		Post()
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the body error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `"error":`) {
+		t.Errorf("expected the body error to be logged, got %s", buf.String())
+	}
+}
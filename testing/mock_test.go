@@ -0,0 +1,70 @@
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestMock_MatchesRequestFields(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("POST", "/users/*").
+		WithQuery("verbose", "1").
+		WithHeader("X-Token", "secret").
+		WithJSONBody(map[string]any{"name": "Alice"}).
+		ReplyJSON(201, map[string]any{"id": 7}).
+		Once()
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	resp, err := client.NewRequest().
+		JoinPath("/users/alice").
+		SetParams(map[string]string{"verbose": "1"}).
+		SetHeaders(map[string]string{"X-Token": "secret"}).
+		SetJsonData(map[string]any{"name": "Alice"}).
+		Post()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode() != 201 {
+		t.Errorf("expected status 201, got %d", resp.StatusCode())
+	}
+	data, err := resp.Json()
+	if err != nil || data["id"] != float64(7) {
+		t.Errorf("unexpected body %v (err %v)", data, err)
+	}
+	transport.AssertExpectations(t)
+}
+
+func TestMock_ErrorsAndUnmatched(t *testing.T) {
+	transport := mock.NewTransport()
+	boom := errors.New("boom")
+	transport.On("GET", "/fail").ReplyError(boom)
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	if _, err := client.NewRequest().JoinPath("/fail").Get(); !errors.Is(err, boom) {
+		t.Errorf("expected transport error, got %v", err)
+	}
+	if _, err := client.NewRequest().JoinPath("/other").Get(); !errors.Is(err, mock.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+
+	recorder := &recordingTB{TB: t}
+	if transport.AssertExpectations(recorder) || len(recorder.errors) != 1 {
+		t.Errorf("expected AssertExpectations to report the unmatched request, got %v", recorder.errors)
+	}
+}
+
+// recordingTB captures errors reported through testing.TB instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
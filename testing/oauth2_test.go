@@ -0,0 +1,97 @@
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+)
+
+func TestOAuth2_ClientCredentialsAndRefresh(t *testing.T) {
+	var issued atomic.Int32
+	var mu sync.Mutex
+	grants := []string{}
+	revoked := map[string]bool{}
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "app" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		r.ParseForm()
+		mu.Lock()
+		grants = append(grants, r.PostForm.Get("grant_type"))
+		mu.Unlock()
+
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("token-%d", n),
+			"token_type":    "bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh",
+		})
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		rejected := revoked[auth]
+		mu.Unlock()
+		if rejected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, auth)
+	}))
+	defer apiServer.Close()
+
+	client := requesto.NewClient(apiServer.URL)
+	client.Use(middleware.NewOAuth2(middleware.OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "app",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"read"},
+	}))
+
+	// Concurrent workers share a single token request.
+	manager := requesto.NewManager(client, requesto.WithPoolSize(8))
+	for i := range 20 {
+		manager.AddURLs(fmt.Sprintf("%s/items/%d", apiServer.URL, i))
+	}
+	for _, result := range manager.Run() {
+		if result.Error != nil {
+			t.Fatalf("request failed: %v", result.Error)
+		}
+		if text, _ := result.Response.Text(); text != "Bearer token-1" {
+			t.Errorf("unexpected authorization %q", text)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("expected a single token request, got %d", issued.Load())
+	}
+
+	// A revoked token is refreshed on 401 and the request is resent.
+	mu.Lock()
+	revoked["Bearer token-1"] = true
+	mu.Unlock()
+	resp, err := client.Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if text, _ := resp.Text(); text != "Bearer token-2" {
+		t.Errorf("expected the refreshed token, got %q", text)
+	}
+	if grants[len(grants)-1] != middleware.GrantRefreshToken {
+		t.Errorf("expected the refresh_token grant to be used, got %v", grants)
+	}
+}
@@ -0,0 +1,75 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestClientStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL,
+		requesto.WithMaxIdleConnsPerHost(2),
+		requesto.WithMaxConnsPerHost(4),
+		requesto.WithIdleConnTimeout(time.Minute),
+		requesto.WithKeepAlive(15*time.Second),
+		requesto.WithResponseHeaderTimeout(5*time.Second),
+		requesto.WithDisableCompression(true),
+	)
+	for range 3 {
+		if _, err := client.NewRequest().Get(); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+
+	// The connection is returned to the pool asynchronously.
+	deadline := time.Now().Add(time.Second)
+	stats := client.Stats()
+	for stats.IdleConns != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		stats = client.Stats()
+	}
+
+	want := requesto.Stats{
+		OpenConns:   1,
+		ActiveConns: 0,
+		IdleConns:   1,
+		Dials:       1,
+		Requests:    3,
+		Reused:      2,
+		ReuseRatio:  2.0 / 3.0,
+	}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	server.CloseClientConnections()
+	deadline = time.Now().Add(time.Second)
+	for client.Stats().OpenConns != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := client.Stats(); stats.OpenConns != 0 || stats.IdleConns != 0 {
+		t.Errorf("after close: %+v", stats)
+	}
+}
+
+func TestPoolOptionsWithCustomTransport(t *testing.T) {
+	client := requesto.NewClient("http://example.test",
+		requesto.WithTransport(mock.NewTransport()),
+		requesto.WithMaxIdleConns(10),
+	)
+	if _, err := client.NewRequest().Get(); err == nil {
+		t.Error("expected an error for a pool option on a custom transport")
+	}
+	if stats := client.Stats(); stats != (requesto.Stats{}) {
+		t.Errorf("Stats() = %+v, want zero", stats)
+	}
+}
@@ -0,0 +1,95 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+// newProxyServer returns a forward proxy stub that answers every request
+// with its own name and the requested URL.
+func newProxyServer(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.URL.String() + " " + r.Header.Get("Proxy-Authorization")))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// text returns the body of resp as a string.
+func text(t *testing.T, resp *requesto.Response) string {
+	t.Helper()
+	body, err := resp.Text()
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return body
+}
+
+func TestProxy(t *testing.T) {
+	first := newProxyServer(t, "first")
+	second := newProxyServer(t, "second")
+
+	authURL := strings.Replace(first.URL, "http://", "http://user:pass@", 1)
+	client := requesto.NewClient("http://origin.invalid", requesto.WithProxy(authURL))
+
+	resp, err := client.NewRequest().JoinPath("/a").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got, want := text(t, resp), "first http://origin.invalid/a Basic dXNlcjpwYXNz"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	resp, err = client.NewRequest().JoinPath("/b").SetProxy(second.URL).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); !strings.HasPrefix(got, "second http://origin.invalid/b") {
+		t.Errorf("per-request proxy not used: %q", got)
+	}
+}
+
+func TestProxyPool(t *testing.T) {
+	first := newProxyServer(t, "first")
+	second := newProxyServer(t, "second")
+
+	pool, err := requesto.NewProxyPool(first.URL, second.URL)
+	if err != nil {
+		t.Fatalf("NewProxyPool: %v", err)
+	}
+	client := requesto.NewClient("http://origin.invalid", requesto.WithProxyPool(pool))
+
+	var names []string
+	for range 3 {
+		resp, err := client.NewRequest().Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		name, _, _ := strings.Cut(text(t, resp), " ")
+		names = append(names, name)
+	}
+	if got := strings.Join(names, ","); got != "first,second,first" {
+		t.Errorf("rotation = %s", got)
+	}
+
+	if !pool.Remove(first.URL) || pool.Len() != 1 {
+		t.Errorf("Remove failed, %d proxies left", pool.Len())
+	}
+}
+
+func TestProxyErrors(t *testing.T) {
+	client := requesto.NewClient("http://origin.invalid", requesto.WithProxy("ftp://proxy:21"))
+	if _, err := client.NewRequest().Get(); err == nil || !strings.Contains(err.Error(), "unsupported proxy scheme") {
+		t.Errorf("expected scheme error, got %v", err)
+	}
+
+	client = requesto.NewClient("http://origin.invalid", requesto.WithTransport(mock.NewTransport()))
+	if _, err := client.NewRequest().SetProxy("socks5://127.0.0.1:1080").Get(); err == nil {
+		t.Error("expected an error for a per-request proxy on a custom transport")
+	}
+}
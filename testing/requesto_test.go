@@ -1,13 +1,29 @@
 package testing
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+	"github.com/Kaguya233qwq/requesto/mock"
 )
 
 func TestClient_NewRequest(t *testing.T) {
-	resp, err := requesto.Get("https://www.baidu.com")
+	transport := mock.NewTransport()
+	transport.On("GET", "https://www.baidu.com/").Reply(200, "<html>baidu</html>").Once()
+
+	client := requesto.NewClient("https://www.baidu.com/", requesto.WithTransport(transport))
+	resp, err := client.Get()
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
@@ -20,4 +36,350 @@ func TestClient_NewRequest(t *testing.T) {
 	} else {
 		t.Logf("Response text: %s", text)
 	}
+	transport.AssertExpectations(t)
+}
+
+func TestClient_Methods(t *testing.T) {
+	transport := mock.NewTransport()
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "PURGE"} {
+		transport.On(method, "/items").Reply(200, method).Once()
+	}
+
+	send := map[string]func(*requesto.Request) (*requesto.Response, error){
+		"GET":     (*requesto.Request).Get,
+		"POST":    (*requesto.Request).Post,
+		"PUT":     (*requesto.Request).Put,
+		"PATCH":   (*requesto.Request).Patch,
+		"DELETE":  (*requesto.Request).Delete,
+		"HEAD":    (*requesto.Request).Head,
+		"OPTIONS": (*requesto.Request).Options,
+		"PURGE": func(r *requesto.Request) (*requesto.Response, error) {
+			return r.Send("purge")
+		},
+	}
+	for method, fn := range send {
+		resp, err := fn(client.NewRequest().JoinPath("/items"))
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		text, _ := resp.Text()
+		if method == "HEAD" {
+			if text != "" {
+				t.Errorf("expected empty HEAD body, got %q", text)
+			}
+		} else if text != method {
+			t.Errorf("%s: expected body %q, got %q", method, method, text)
+		}
+	}
+	transport.AssertExpectations(t)
+}
+
+func TestClient_RaiseForStatus(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/flaky").Reply(503, "unavailable").Times(2)
+	transport.On("GET", "/flaky").Reply(200, "ok").Once()
+
+	client := requesto.NewClient(
+		"https://api.example.com",
+		requesto.WithTransport(transport),
+		requesto.WithRaiseForStatus(true),
+	)
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		RetryCount:   3,
+		RetryBackoff: time.Millisecond,
+		RetryIf: func(resp *requesto.Response, err error) bool {
+			var httpErr *requesto.HTTPError
+			return errors.As(err, &httpErr) && httpErr.StatusCode >= 500
+		},
+	}))
+
+	resp, err := client.NewRequest().JoinPath("/flaky").Get()
+	if err != nil {
+		t.Fatalf("expected the retrier to recover, got %v", err)
+	}
+	if resp.StatusCode() != 200 {
+		t.Errorf("expected status 200, got %d", resp.StatusCode())
+	}
+
+	transport.On("DELETE", "/missing").Reply(404, "not found")
+	resp, err = client.NewRequest().JoinPath("/missing").SetAPIKey("api_key", "k3y", requesto.APIKeyInQuery).Delete()
+	var httpErr *requesto.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if httpErr.Method != "DELETE" || httpErr.StatusCode != 404 || string(httpErr.Body) != "not found" {
+		t.Errorf("unexpected error fields: %+v", httpErr)
+	}
+	if strings.Contains(err.Error(), "k3y") || httpErr.URL != "https://api.example.com/missing?api_key=REDACTED" {
+		t.Errorf("expected the API key to be redacted, got URL %q", httpErr.URL)
+	}
+	if resp == nil || resp.StatusCode() != 404 {
+		t.Error("expected the response to be returned alongside the error")
+	}
+	transport.AssertExpectations(t)
+}
+
+// trackedBody records whether a response body was closed.
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestClient_RaiseForStatusClosesStream(t *testing.T) {
+	var bodies []*trackedBody
+	transport := mock.NewTransport()
+	transport.On("GET", "/fail").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		resp := mock.NewResponse(req, 500, http.Header{"Content-Type": {"application/json"}}, nil)
+		body := &trackedBody{Reader: strings.NewReader(strings.Repeat("x", 1024))}
+		bodies = append(bodies, body)
+		resp.Body = body
+		return resp, nil
+	})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport), requesto.WithStream(true))
+
+	resp, err := client.NewRequest().JoinPath("/fail").SetRaiseForStatus(true).Get()
+	var httpErr *requesto.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if len(httpErr.Body) != 512 {
+		t.Errorf("expected a 512-byte body snippet, got %d bytes", len(httpErr.Body))
+	}
+	// The response does not pass the snippet off as its whole body.
+	if text, err := resp.Text(); !errors.Is(err, requesto.ErrReadingBody) || text != "" {
+		t.Errorf("expected reading the released body to fail, got %q, %v", text, err)
+	}
+	if resp.StatusCode() != 500 {
+		t.Errorf("expected status 500, got %d", resp.StatusCode())
+	}
+
+	if _, err := requesto.GetJSON[map[string]any](client, "/fail"); !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError from GetJSON, got %v", err)
+	}
+
+	for i, body := range bodies {
+		if !body.closed {
+			t.Errorf("response %d: streaming body was not closed", i)
+		}
+	}
+}
+
+func TestResponse_StreamLazyLoad(t *testing.T) {
+	var bodies []*trackedBody
+	var readers []*strings.Reader
+	transport := mock.NewTransport()
+	transport.On("GET", "/data").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		resp := mock.NewResponse(req, 200, nil, nil)
+		reader := strings.NewReader("streamed body")
+		body := &trackedBody{Reader: reader}
+		readers = append(readers, reader)
+		bodies = append(bodies, body)
+		resp.Body = body
+		return resp, nil
+	})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport), requesto.WithStream(true))
+
+	// Text drains and closes the stream on first use, then serves the buffer.
+	resp, err := client.NewRequest().JoinPath("/data").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if !resp.IsStream() || readers[0].Len() != len("streamed body") {
+		t.Fatal("expected the body to be left unread until accessed")
+	}
+	for range 2 {
+		if text, err := resp.Text(); err != nil || text != "streamed body" {
+			t.Errorf("Text() = %q, %v", text, err)
+		}
+	}
+	if resp.IsStream() || !bodies[0].closed {
+		t.Error("expected Text to drain and close the stream")
+	}
+
+	// CopyTo writes straight from the stream.
+	resp, err = client.NewRequest().JoinPath("/data").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if n, err := resp.CopyTo(&buf); err != nil || n != int64(len("streamed body")) || buf.String() != "streamed body" {
+		t.Errorf("CopyTo wrote %d bytes %q, %v", n, buf.String(), err)
+	}
+	if !bodies[1].closed {
+		t.Error("expected CopyTo to close the stream")
+	}
+
+	// SaveTo writes the stream to a file.
+	resp, err = client.NewRequest().JoinPath("/data").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "body.txt")
+	if err := resp.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "streamed body" {
+		t.Errorf("saved file contains %q, %v", data, err)
+	}
+	if !bodies[2].closed {
+		t.Error("expected SaveTo to close the stream")
+	}
+
+	// A buffered client reads the body eagerly.
+	resp, err = client.NewRequest().JoinPath("/data").SetStream(false).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.IsStream() || !bodies[3].closed {
+		t.Error("expected a buffered response to be read and closed immediately")
+	}
+}
+
+func TestResponse_StreamCloseReleasesConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), 1<<20))
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithStream(true))
+	resp, err := client.Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if stats := client.Stats(); stats.ActiveConns != 1 {
+		t.Fatalf("expected the stream to hold its connection, got %+v", stats)
+	}
+
+	if err := resp.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := resp.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for client.Stats().ActiveConns != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := client.Stats(); stats.ActiveConns != 0 {
+		t.Errorf("expected Close to release the connection, got %+v", stats)
+	}
+}
+
+func TestClient_SetErrorResult(t *testing.T) {
+	type apiError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	transport := mock.NewTransport()
+	transport.On("GET", "/users/1").ReplyJSON(200, map[string]any{"name": "Alice"})
+	transport.On("GET", "/users/2").ReplyJSON(404, map[string]any{"code": "not_found", "message": "no such user"})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.SetErrorResult(&apiError{})
+
+	resp, err := client.NewRequest().JoinPath("/users/1").Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, err := requesto.ToStruct[user](resp); err != nil || u.Name != "Alice" {
+		t.Errorf("unexpected success result %+v (err %v)", u, err)
+	}
+
+	resp, err = client.NewRequest().JoinPath("/users/2").Get()
+	var httpErr *requesto.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	apiErr, ok := httpErr.Result.(*apiError)
+	if !ok || apiErr.Code != "not_found" {
+		t.Errorf("unexpected error result %#v", httpErr.Result)
+	}
+	if resp.ErrorResult() != httpErr.Result {
+		t.Error("expected the response to expose the same error result")
+	}
+	var u user
+	if err := resp.Unmarshal(&u); !errors.As(err, &httpErr) || u.Name != "" {
+		t.Errorf("expected Unmarshal to refuse a failed response, got %v", err)
+	}
+}
+
+func TestRequest_TraceInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithTrace(true))
+	first, err := client.Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	info := first.TraceInfo()
+	if info.ConnReused || info.TCPConnect <= 0 || info.ServerTime < 5*time.Millisecond || info.TotalTime < info.TimeToFirstByte {
+		t.Errorf("unexpected trace for a new connection: %+v", info)
+	}
+
+	second, err := client.Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if info := second.TraceInfo(); !info.ConnReused || info.TCPConnect != 0 {
+		t.Errorf("expected the second request to reuse the connection: %+v", info)
+	}
+
+	untraced, _ := client.NewRequest().SetTrace(false).Get()
+	if untraced.TraceInfo() != (requesto.TraceInfo{}) {
+		t.Error("expected no trace when disabled on the request")
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.Query().Get("q"), r.Header.Get("X-Test"), body)
+	}))
+	defer server.Close()
+
+	resp, err := requesto.Send("purge", server.URL,
+		requesto.Params{"q": "1"},
+		requesto.Headers{"X-Test": "yes"},
+		requesto.AsForm(map[string]string{"a": "b"}),
+	)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if text, _ := resp.Text(); text != "PURGE 1 yes a=b" {
+		t.Errorf("unexpected echo %q", text)
+	}
+
+	// Body arguments are ignored for methods without a body.
+	resp, err = requesto.Send("GET", server.URL, requesto.AsForm(map[string]string{"a": "b"}))
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if text, _ := resp.Text(); text != "GET   " {
+		t.Errorf("unexpected echo %q", text)
+	}
+
+	if _, err := requesto.Send("", server.URL); err == nil {
+		t.Error("expected an error for an empty method")
+	}
+	if _, err := requesto.Send("POST", server.URL, 42); err == nil {
+		t.Error("expected an error for an unsupported argument")
+	}
 }
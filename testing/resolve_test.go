@@ -0,0 +1,117 @@
+package testing
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// newDNSServer starts a UDP DNS server that answers every A query with
+// 127.0.0.1 and returns a resolver using it and a counter of queries.
+func newDNSServer(t *testing.T) (*net.Resolver, *atomic.Int32) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+			// Header, then the question up to the end of the QNAME.
+			end := 12
+			for end < n && buf[end] != 0 {
+				end += int(buf[end]) + 1
+			}
+			qtype := binary.BigEndian.Uint16(buf[end+1:])
+			question := buf[12 : end+5]
+
+			resp := append([]byte{}, buf[:2]...)
+			resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+			resp = append(resp, question...)
+			if qtype == 1 {
+				resp[7] = 1
+				resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+	return resolver, &queries
+}
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := requesto.NewClient("http://blue.example.test:"+port, requesto.WithResolve(map[string]string{
+		"blue.example.test:" + port: "127.0.0.1",
+		"green.example.test":        server.Listener.Addr().String(),
+	}))
+	resp, err := client.NewRequest().Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "blue.example.test:"+port {
+		t.Errorf("Host = %q", got)
+	}
+
+	resp, err = client.NewRequest().SetURL("http://green.example.test/").Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "green.example.test" {
+		t.Errorf("Host = %q", got)
+	}
+}
+
+func TestResolverAndDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Force a new connection, and so a new lookup, for every request.
+		w.Header().Set("Connection", "close")
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver, queries := newDNSServer(t)
+	client := requesto.NewClient("http://api.internal.test:"+port,
+		requesto.WithResolver(resolver),
+		requesto.WithDNSCache(time.Minute),
+	)
+	if _, err := client.NewRequest().Get(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	first := queries.Load()
+	if first == 0 {
+		t.Fatal("custom resolver was not used")
+	}
+	if _, err := client.NewRequest().Get(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := queries.Load(); got != first {
+		t.Errorf("queries = %d after a cached lookup, want %d", got, first)
+	}
+}
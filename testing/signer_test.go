@@ -0,0 +1,99 @@
+package testing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+func TestAWSV4Signer(t *testing.T) {
+	// The get-vanilla case from the AWS Signature Version 4 test suite.
+	u, _ := url.Parse("https://example.amazonaws.com/")
+	sr := &middleware.SigningRequest{
+		Method:   "GET",
+		URL:      u,
+		Header:   make(http.Header),
+		BodyHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		Time:     time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC),
+	}
+	signer := &middleware.AWSV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+	}
+	if err := signer.Sign(sr); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := sr.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if got := sr.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSignerMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	var seen *http.Request
+	var body string
+
+	transport := mock.NewTransport()
+	transport.On("POST", "/orders").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		seen = req
+		raw, err := io.ReadAll(req.Body)
+		body = string(raw)
+		return mock.NewResponse(req, 200, nil, nil), err
+	})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+	client.Use(middleware.NewSigner(&middleware.HMACSigner{
+		KeyID:         "key-1",
+		Secret:        secret,
+		SignedHeaders: []string{"Content-Type"},
+	}))
+
+	_, err := client.NewRequest().JoinPath("/orders").SetJsonData(map[string]any{"id": 1}).Post()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if seen == nil {
+		t.Fatal("request was not sent")
+	}
+	if body != `{"id":1}` {
+		t.Errorf("body = %q", body)
+	}
+
+	sum := sha256.Sum256([]byte(body))
+	date := seen.Header.Get("X-Date")
+	stringToSign := "POST\n/orders\n" + date + "\n" +
+		"x-date:" + date + "\n" +
+		"content-type:application/json; charset=utf-8\n" +
+		hex.EncodeToString(sum[:])
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	want := "HMAC-SHA256 KeyId=key-1, SignedHeaders=x-date;content-type, Signature=" +
+		base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if got := seen.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(seen.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("Content-Type = %q", seen.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,154 @@
+package testing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+func TestRequest_SSE(t *testing.T) {
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connections == 1 {
+			if got := r.Header.Get("Last-Event-ID"); got != "" {
+				t.Errorf("unexpected Last-Event-ID on first connection: %q", got)
+			}
+			fmt.Fprint(w, ": comment\n")
+			fmt.Fprint(w, "retry: 10\n")
+			fmt.Fprint(w, "id: 1\nevent: greeting\ndata: hello\ndata: world\n\n")
+			return
+		}
+		if got := r.Header.Get("Last-Event-ID"); got != "1" {
+			t.Errorf("expected Last-Event-ID 1 on reconnect, got %q", got)
+		}
+		fmt.Fprint(w, "id: 2\r\ndata: again\r\n\r\n")
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL)
+	stream, err := client.NewRequest().SSE(requesto.WithSSEReconnect(1), requesto.WithSSERetry(time.Millisecond))
+	if err != nil {
+		t.Fatalf("SSE failed: %v", err)
+	}
+
+	var events []requesto.Event
+	for event := range stream.All() {
+		events = append(events, event)
+		if len(events) == 2 {
+			break
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if e := events[0]; e.ID != "1" || e.Event != "greeting" || e.Data != "hello\nworld" || e.Retry != 10*time.Millisecond {
+		t.Errorf("unexpected first event: %+v", e)
+	}
+	if e := events[1]; e.ID != "2" || e.Event != "message" || e.Data != "again" {
+		t.Errorf("unexpected second event: %+v", e)
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("expected no error after Close, got %v", err)
+	}
+}
+
+func TestRequest_SSERejectsOtherContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+
+	_, err := requesto.NewClient(server.URL).NewRequest().SSE()
+	if err == nil {
+		t.Fatal("expected an error for a non event-stream response")
+	}
+}
+
+func TestRequest_SSEStopsOnHTTPErrors(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		var connections atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if connections.Add(1) > 1 {
+				w.WriteHeader(status)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: first\n\n")
+		}))
+
+		// Unlimited reconnects must still stop on an HTTP error.
+		stream, err := requesto.NewClient(server.URL).NewRequest().SSE(
+			requesto.WithSSEReconnect(-1), requesto.WithSSERetry(time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("SSE failed: %v", err)
+		}
+		done := make(chan struct{})
+		go func() {
+			for range stream.Events() {
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			stream.Close()
+			t.Fatalf("status %d: stream kept reconnecting", status)
+		}
+
+		if got := connections.Load(); got != 2 {
+			t.Errorf("status %d: %d connections, want 2", status, got)
+		}
+		err = stream.Err()
+		if status == http.StatusNoContent && err != nil {
+			t.Errorf("204 should end the stream cleanly, got %v", err)
+		}
+		if status == http.StatusNotFound && err == nil {
+			t.Error("404 should end the stream with an error")
+		}
+		server.Close()
+	}
+}
+
+func TestRequest_SSEOutlivesClientTimeout(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range 5 {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithTimeout(30*time.Millisecond))
+	stream, err := client.NewRequest().SSE(requesto.WithSSERetry(time.Millisecond))
+	if err != nil {
+		t.Fatalf("SSE failed: %v", err)
+	}
+	defer stream.Close()
+
+	count := 0
+	for range stream.All() {
+		if count++; count == 5 {
+			break
+		}
+	}
+	if count != 5 {
+		t.Fatalf("expected 5 events, got %d (err %v)", count, stream.Err())
+	}
+	if got := connections.Load(); got != 1 {
+		t.Errorf("expected the stream to outlive the client timeout on one connection, got %d connections", got)
+	}
+}
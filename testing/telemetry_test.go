@@ -0,0 +1,72 @@
+package testing
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/middleware"
+	"github.com/Kaguya233qwq/requesto/mock"
+	"github.com/Kaguya233qwq/requesto/telemetry"
+)
+
+func TestTelemetry_SpansAndMetrics(t *testing.T) {
+	var traceparents []string
+	transport := mock.NewTransport()
+	transport.On("GET", "/traced").Match(func(req *http.Request) bool {
+		traceparents = append(traceparents, req.Header.Get("Traceparent"))
+		return true
+	}).Reply(503, "busy").Once()
+	transport.On("GET", "/traced").Reply(200, "ok").Once()
+
+	exporter := telemetry.NewExporter()
+	client := requesto.NewClient(
+		"https://api.example.com",
+		requesto.WithTransport(transport),
+		requesto.WithTracer(exporter.Tracer()),
+		requesto.WithMeter(exporter.Meter()),
+	)
+	client.Use(middleware.NewRetrier(middleware.RetryPolicy{
+		RetryBackoff: time.Millisecond,
+		RetryIf: func(resp *requesto.Response, err error) bool {
+			return err != nil || resp.StatusCode() >= 500
+		},
+	}))
+
+	if _, err := client.NewRequest().JoinPath("/traced").Get(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 2 attempt spans and 1 request span, got %d", len(spans))
+	}
+	root := spans[2]
+	if root.Name != "HTTP GET" || root.Attributes["http.response.status_code"] != 200 {
+		t.Errorf("unexpected request span %+v", root)
+	}
+	for i, attempt := range spans[:2] {
+		if attempt.Parent != root.Context {
+			t.Errorf("attempt %d is not a child of the request span", i+1)
+		}
+		if attempt.Attributes["http.request.attempt"] != i+1 {
+			t.Errorf("unexpected attempt number %v", attempt.Attributes["http.request.attempt"])
+		}
+	}
+
+	sc, ok := requesto.ParseTraceParent(traceparents[0])
+	if !ok || sc != spans[0].Context {
+		t.Errorf("traceparent %q does not match the first attempt span", traceparents[0])
+	}
+
+	durations := 0
+	for _, m := range exporter.Measurements() {
+		if m.Name == requesto.MetricRequestDuration {
+			durations++
+		}
+	}
+	if durations != 2 {
+		t.Errorf("expected one duration per attempt, got %d", durations)
+	}
+}
@@ -0,0 +1,184 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// expectTimeout fails the test unless err is a *TimeoutError of the given kind.
+func expectTimeout(t *testing.T, err error, kind requesto.TimeoutKind) {
+	t.Helper()
+	var timeoutErr *requesto.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected *TimeoutError, got %v", err)
+	}
+	if timeoutErr.Kind != kind {
+		t.Errorf("Kind = %v, want %v (%v)", timeoutErr.Kind, kind, err)
+	}
+}
+
+// stall blocks until the request is canceled or a second has passed.
+func stall(r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-time.After(time.Second):
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			stall(r)
+		}
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithTimeout(50*time.Millisecond))
+	_, err := client.NewRequest().JoinPath("/slow").Get()
+	expectTimeout(t, err, requesto.TimeoutRequest)
+
+	_, err = client.NewRequest().JoinPath("/slow").SetTimeout(20 * time.Millisecond).Get()
+	expectTimeout(t, err, requesto.TimeoutRequest)
+
+	if _, err := client.NewRequest().JoinPath("/fast").SetTimeout(time.Second).Get(); err != nil {
+		t.Errorf("request failed: %v", err)
+	}
+}
+
+func TestRequestTimeoutWhileReadingBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		stall(r)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithTimeout(50*time.Millisecond))
+	for _, req := range []*requesto.Request{client.NewRequest(), client.NewRequest().SetTimeout(20 * time.Millisecond)} {
+		resp, err := req.Get()
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, err = resp.Text()
+		if !errors.Is(err, requesto.ErrReadingBody) {
+			t.Errorf("expected ErrReadingBody, got %v", err)
+		}
+		expectTimeout(t, err, requesto.TimeoutRequest)
+	}
+
+	// A deadline of the caller's context is not one of the client's timeouts.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	resp, err := client.NewRequestWithContext(ctx).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, err = resp.Text()
+	var timeoutErr *requesto.TimeoutError
+	if errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the caller's context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRequestTimeoutSparesStreamedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 5 {
+			w.Write([]byte("chunk "))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	// The body takes longer than the timeout, which only covers the headers.
+	client := requesto.NewClient(server.URL, requesto.WithTimeout(50*time.Millisecond), requesto.WithStream(true))
+	resp, err := client.Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Close()
+	if text, err := resp.Text(); err != nil || text != strings.Repeat("chunk ", 5) {
+		t.Errorf("Text() = %q, %v", text, err)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stall(r)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithResponseHeaderTimeout(50*time.Millisecond))
+	_, err := client.NewRequest().Get()
+	expectTimeout(t, err, requesto.TimeoutResponseHeader)
+}
+
+func TestIdleReadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		stall(r)
+	}))
+	defer server.Close()
+
+	client := requesto.NewClient(server.URL, requesto.WithIdleReadTimeout(50*time.Millisecond))
+	resp, err := client.NewRequest().SetStream(true).Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Close()
+
+	body, err := io.ReadAll(resp.Body())
+	if string(body) != "partial" {
+		t.Errorf("body = %q", body)
+	}
+	expectTimeout(t, err, requesto.TimeoutIdleRead)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	client := requesto.NewClient("http://service.test",
+		requesto.WithDialContext(func(ctx context.Context, network, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		requesto.WithConnectTimeout(50*time.Millisecond),
+	)
+	_, err := client.NewRequest().Get()
+	expectTimeout(t, err, requesto.TimeoutConnect)
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	// A listener that accepts connections but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	client := requesto.NewClient("https://"+listener.Addr().String(),
+		requesto.WithTLSHandshakeTimeout(50*time.Millisecond),
+	)
+	_, err = client.NewRequest().Get()
+	expectTimeout(t, err, requesto.TimeoutTLSHandshake)
+}
@@ -0,0 +1,195 @@
+package testing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kaguya233qwq/requesto"
+)
+
+// writeClientCertificate writes a self-signed client certificate with the
+// given common name and its key to dir, returning the certificate.
+func writeClientCertificate(t *testing.T, dir, commonName string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, "client.crt"), certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "client.key"), keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	first := writeClientCertificate(t, dir, "first")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	// The client certificate changes during the test, so verify it by hand.
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := requesto.NewClient(server.URL,
+		requesto.WithRootCAsFromFile(caFile),
+		requesto.WithClientCertificate(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")),
+		requesto.WithMinTLSVersion(tls.VersionTLS12),
+		requesto.WithServerName("example.com"),
+		requesto.WithPinnedPublicKeys("sha256/"+requesto.PublicKeyPin(server.Certificate())),
+	)
+	resp, err := client.NewRequest().Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != first.Subject.CommonName {
+		t.Errorf("client certificate = %q, want %q", got, first.Subject.CommonName)
+	}
+
+	// Rotate the certificate and force a new handshake.
+	writeClientCertificate(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "client.crt"), future, future)
+	server.CloseClientConnections()
+
+	resp, err = client.NewRequest().Get()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := text(t, resp); got != "second" {
+		t.Errorf("client certificate after rotation = %q, want %q", got, "second")
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's certificate is not trusted by default.
+	if _, err := requesto.NewClient(server.URL).NewRequest().Get(); err == nil {
+		t.Error("expected a certificate verification error")
+	}
+
+	client := requesto.NewClient(server.URL, requesto.WithInsecureSkipVerify(true))
+	if _, err := client.NewRequest().Get(); err != nil {
+		t.Errorf("insecure request failed: %v", err)
+	}
+
+	client = requesto.NewClient(server.URL,
+		requesto.WithInsecureSkipVerify(true),
+		requesto.WithPinnedPublicKeys("sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+	)
+	_, err := client.NewRequest().Get()
+	var pinErr *requesto.PinningError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("expected *PinningError, got %v", err)
+	}
+	if len(pinErr.Pins) == 0 || pinErr.Pins[0] != "sha256/"+requesto.PublicKeyPin(server.Certificate()) {
+		t.Errorf("unexpected pins %v", pinErr.Pins)
+	}
+
+	client = requesto.NewClient(server.URL, requesto.WithRootCAs([]byte("not a certificate")))
+	if _, err := client.NewRequest().Get(); err == nil {
+		t.Error("expected an error for invalid CA data")
+	}
+}
+
+// newServerCertificate returns a self-signed certificate for 127.0.0.1.
+func newServerCertificate(t *testing.T, commonName string) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, cert
+}
+
+func TestPinningRejectsAppendedCertificate(t *testing.T) {
+	_, pinnedCert := newServerCertificate(t, "pinned")
+	attacker, attackerCert := newServerCertificate(t, "attacker")
+	// The attacker sends the public pinned certificate after its own.
+	attacker.Certificate = append(attacker.Certificate, pinnedCert.Raw)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{attacker}}
+	server.StartTLS()
+	defer server.Close()
+
+	pin := requesto.WithPinnedPublicKeys(requesto.PublicKeyPin(pinnedCert))
+	attackerPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: attackerCert.Raw})
+	clients := map[string]*requesto.Client{
+		"insecure": requesto.NewClient(server.URL, requesto.WithInsecureSkipVerify(true), pin),
+		"verified": requesto.NewClient(server.URL, requesto.WithRootCAs(attackerPEM), pin),
+	}
+	for name, client := range clients {
+		_, err := client.NewRequest().Get()
+		var pinErr *requesto.PinningError
+		if !errors.As(err, &pinErr) {
+			t.Errorf("%s: expected *PinningError, got %v", name, err)
+		}
+	}
+}
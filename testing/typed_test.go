@@ -0,0 +1,49 @@
+package testing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kaguya233qwq/requesto"
+	"github.com/Kaguya233qwq/requesto/mock"
+)
+
+type typedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedHelpers(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.On("GET", "/users/1").WithQuery("expand", "true").ReplyJSON(200, typedUser{ID: 1, Name: "Alice"})
+	transport.On("POST", "/users").WithJSONBody(typedUser{Name: "Bob"}).ReplyJSON(201, typedUser{ID: 2, Name: "Bob"})
+	transport.On("GET", "/html").Reply(200, "<html></html>").ReplyHeader("Content-Type", "text/html")
+	transport.On("GET", "/missing").ReplyJSON(404, map[string]string{"error": "missing"})
+
+	client := requesto.NewClient("https://api.example.com", requesto.WithTransport(transport))
+
+	user, err := requesto.GetJSON[typedUser](client, "/users/1", requesto.Params{"expand": "true"})
+	if err != nil || user.Name != "Alice" {
+		t.Errorf("GetJSON returned %+v, %v", user, err)
+	}
+
+	created, err := requesto.PostJSON[typedUser, typedUser](client, "/users", typedUser{Name: "Bob"})
+	if err != nil || created.ID != 2 {
+		t.Errorf("PostJSON returned %+v, %v", created, err)
+	}
+
+	// A nil pointer is sent as no body rather than as JSON null.
+	transport.On("PUT", "/users/3").WithBody("").ReplyJSON(200, typedUser{ID: 3})
+	if updated, err := requesto.PutJSON[*typedUser, typedUser](client, "/users/3", nil); err != nil || updated.ID != 3 {
+		t.Errorf("PutJSON with a nil pointer returned %+v, %v", updated, err)
+	}
+
+	if _, err := requesto.GetJSON[typedUser](client, "/html"); !errors.Is(err, requesto.ErrUnexpectedContentType) {
+		t.Errorf("expected ErrUnexpectedContentType, got %v", err)
+	}
+
+	var httpErr *requesto.HTTPError
+	if _, err := requesto.GetJSON[typedUser](client, "/missing"); !errors.As(err, &httpErr) || httpErr.StatusCode != 404 {
+		t.Errorf("expected a 404 *HTTPError, got %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+package requesto
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TimeoutKind identifies which timeout expired.
+type TimeoutKind int
+
+const (
+	// TimeoutRequest is the overall timeout set with WithTimeout or Request.SetTimeout.
+	TimeoutRequest TimeoutKind = iota
+	// TimeoutConnect is the timeout for opening a connection, see WithConnectTimeout.
+	TimeoutConnect
+	// TimeoutTLSHandshake is the timeout for the TLS handshake, see WithTLSHandshakeTimeout.
+	TimeoutTLSHandshake
+	// TimeoutResponseHeader is the timeout for receiving the response headers,
+	// see WithResponseHeaderTimeout.
+	TimeoutResponseHeader
+	// TimeoutIdleRead is the timeout for a stalled response body, see WithIdleReadTimeout.
+	TimeoutIdleRead
+)
+
+// String returns the name of the timeout.
+func (k TimeoutKind) String() string {
+	switch k {
+	case TimeoutRequest:
+		return "request"
+	case TimeoutConnect:
+		return "connect"
+	case TimeoutTLSHandshake:
+		return "TLS handshake"
+	case TimeoutResponseHeader:
+		return "response header"
+	case TimeoutIdleRead:
+		return "idle read"
+	default:
+		return fmt.Sprintf("TimeoutKind(%d)", int(k))
+	}
+}
+
+// TimeoutError is returned when one of the client's timeouts expires. Use
+// errors.As to retrieve it and inspect Kind. It reports true from Timeout,
+// like other network timeouts.
+type TimeoutError struct {
+	Kind TimeoutKind
+	// Duration is the configured timeout, or 0 if it is not known.
+	Duration time.Duration
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	msg := "requesto: " + e.Kind.String() + " timeout"
+	if e.Duration > 0 {
+		msg += " after " + e.Duration.String()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports true, so TimeoutError satisfies net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports true, as required by net.Error.
+//
+// Deprecated: see net.Error.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// SetTimeout limits the duration of each attempt of the request, from
+// connecting until the response body has been read, overriding the client
+// timeout set with WithTimeout. In streaming mode, including SSE, the timeout
+// stops once the response headers have arrived, so long downloads and event
+// streams are not cut off; use SetIdleReadTimeout to detect a stalled body.
+// Zero restores the client timeout.
+func (r *Request) SetTimeout(d time.Duration) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.timeout = d
+	return r
+}
+
+// SetIdleReadTimeout sets the longest the request waits for more data while
+// reading the response body, overriding WithIdleReadTimeout. A stalled
+// body fails with a *TimeoutError of kind TimeoutIdleRead. Zero disables it.
+func (r *Request) SetIdleReadTimeout(d time.Duration) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.idleRead = d
+	return r
+}
+
+// WithConnectTimeout limits the time to open a connection, including the
+// DNS lookup. The default is 30 seconds.
+func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if c.transport("WithConnectTimeout") != nil {
+			c.dialer.connectTimeout = d
+		}
+	}
+}
+
+// WithTLSHandshakeTimeout limits the time of the TLS handshake. Zero means no
+// limit; the default is 10 seconds.
+func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		if t := c.transport("WithTLSHandshakeTimeout"); t != nil {
+			t.TLSHandshakeTimeout = d
+		}
+	}
+}
+
+// WithIdleReadTimeout sets the longest every request waits for more data while
+// reading the response body, which catches streams that stall without
+// limiting their total duration. See Request.SetIdleReadTimeout.
+func WithIdleReadTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.idleReadTimeout = d
+	}
+}
+
+// attemptTimeouts tracks the progress of one attempt to tell which timeout
+// expired.
+type attemptTimeouts struct {
+	mu           sync.Mutex
+	expired      bool // the overall timeout of the attempt expired
+	tlsTimedOut  bool
+	wroteRequest bool
+	gotHeaders   bool
+}
+
+// attemptTimeout returns the overall timeout of an attempt: the request
+// timeout if set, or else the client timeout.
+func (r *Request) attemptTimeout() time.Duration {
+	if r.timeout > 0 {
+		return r.timeout
+	}
+	return r.client.httpClient.Timeout
+}
+
+// startTimeouts applies the overall timeout to req and returns a function
+// that releases it and turns timeout errors, including those that occur while
+// a buffered body is read, into a *TimeoutError.
+func (r *Request) startTimeouts(req *http.Request) (*http.Request, func(*Response, error) error) {
+	parent := req.Context()
+	a := &attemptTimeouts{}
+
+	// The deadline is a timer rather than a context deadline so that it can be
+	// lifted from streaming bodies once the headers have arrived.
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := func() {}
+	if timeout := r.attemptTimeout(); timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			a.mu.Lock()
+			a.expired = true
+			a.mu.Unlock()
+			cancel(context.DeadlineExceeded)
+		})
+		stop = func() { timer.Stop() }
+	}
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if isTimeout(err) {
+				a.mu.Lock()
+				a.tlsTimedOut = true
+				a.mu.Unlock()
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			a.mu.Lock()
+			a.wroteRequest = true
+			a.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			a.mu.Lock()
+			a.gotHeaders = true
+			a.mu.Unlock()
+		},
+	})
+	req = req.WithContext(ctx)
+
+	return req, func(resp *Response, err error) error {
+		stop()
+		classify := func(err error) error {
+			if err == nil || parent.Err() != nil || (!isTimeout(err) && !a.timedOut()) {
+				return err
+			}
+			return r.classifyTimeout(a, err)
+		}
+		if resp != nil && resp.stream != nil {
+			// The body is still being read, so keep the context until it is closed.
+			resp.stream = &cancelOnClose{ReadCloser: resp.stream, cancel: func() { cancel(nil) }, classify: classify}
+		} else {
+			defer cancel(nil)
+		}
+		if resp != nil && resp.stream == nil && resp.err != nil {
+			if classified := classify(resp.err); classified != resp.err {
+				resp.err = fmt.Errorf("%w: %w", ErrReadingBody, classified)
+			}
+		}
+		return classify(err)
+	}
+}
+
+// timedOut reports whether the overall timeout of the attempt expired.
+func (a *attemptTimeouts) timedOut() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.expired
+}
+
+// classifyTimeout wraps the timeout error err of an attempt in a *TimeoutError.
+func (r *Request) classifyTimeout(a *attemptTimeouts, err error) error {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	transport, ok := r.client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{}
+	}
+	switch {
+	case a.expired:
+		return &TimeoutError{Kind: TimeoutRequest, Duration: r.attemptTimeout(), Err: err}
+	case a.tlsTimedOut:
+		return &TimeoutError{Kind: TimeoutTLSHandshake, Duration: transport.TLSHandshakeTimeout, Err: err}
+	case transport.ResponseHeaderTimeout > 0 && a.wroteRequest && !a.gotHeaders:
+		return &TimeoutError{Kind: TimeoutResponseHeader, Duration: transport.ResponseHeaderTimeout, Err: err}
+	}
+	return err
+}
+
+// isTimeout reports whether err is a timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// cancelOnClose releases the context of a streaming response when its body is
+// closed and reports read errors caused by a timeout as a *TimeoutError.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel   context.CancelFunc
+	classify func(error) error
+}
+
+// Read reads from the body.
+func (c *cancelOnClose) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = c.classify(err)
+	}
+	return n, err
+}
+
+// Close closes the body and releases the context.
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// idleReadBody wraps body so that a Read blocking for longer than timeout
+// closes it and fails with a *TimeoutError.
+func idleReadBody(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if timeout <= 0 || body == nil || body == http.NoBody {
+		return body
+	}
+	b := &idleReader{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, b.expire)
+	b.timer.Stop()
+	return b
+}
+
+// idleReader is a response body guarded by an idle-read timeout.
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+// Read reads from the body, failing if no data arrives within the timeout.
+func (b *idleReader) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.body.Read(p)
+	b.timer.Stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.expired && err != nil {
+		err = &TimeoutError{Kind: TimeoutIdleRead, Duration: b.timeout, Err: err}
+	}
+	return n, err
+}
+
+// Close stops the timer and closes the body.
+func (b *idleReader) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
+
+// expire closes the body to unblock a stalled Read.
+func (b *idleReader) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.body.Close()
+}
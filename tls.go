@@ -0,0 +1,251 @@
+package requesto
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PinningError is returned when none of the certificates presented by a
+// server matches the public keys pinned with WithPinnedPublicKeys. Requests
+// wrap it in a *url.Error; use errors.As to retrieve it.
+type PinningError struct {
+	// Host is the server name that was verified.
+	Host string
+	// Pins are the "sha256/<base64>" pins of the presented certificate chain.
+	Pins []string
+}
+
+// Error implements the error interface.
+func (e *PinningError) Error() string {
+	return fmt.Sprintf("requesto: certificate of %q does not match any pinned public key (got %s)", e.Host, strings.Join(e.Pins, ", "))
+}
+
+// tlsConfig returns the TLS configuration of the client's transport,
+// creating it if needed.
+func (c *clientConfig) tlsConfig(option string) *tls.Config {
+	t := c.transport(option)
+	if t == nil {
+		return nil
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
+
+// WithRootCAs trusts the PEM-encoded CA certificates in pemCerts instead of
+// the system roots. It can be combined with WithRootCAsFromFile and called
+// several times to trust more CAs.
+func WithRootCAs(pemCerts []byte) ClientOption {
+	return func(c *clientConfig) {
+		config := c.tlsConfig("WithRootCAs")
+		if config == nil {
+			return
+		}
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pemCerts) {
+			c.setErr(errors.New("requesto: no valid CA certificates found in PEM data"))
+		}
+	}
+}
+
+// WithRootCAsFromFile trusts the CA certificates in the given PEM files
+// instead of the system roots. See WithRootCAs.
+func WithRootCAsFromFile(paths ...string) ClientOption {
+	return func(c *clientConfig) {
+		for _, path := range paths {
+			pemCerts, err := os.ReadFile(path)
+			if err != nil {
+				c.setErr(fmt.Errorf("requesto: reading CA file: %w", err))
+				return
+			}
+			WithRootCAs(pemCerts)(c)
+		}
+	}
+}
+
+// WithClientCertificate presents the certificate and private key in the given
+// PEM files to servers that request client authentication (mutual TLS).
+// The files are reloaded when their modification time changes, so rotated
+// certificates are picked up without recreating the client.
+func WithClientCertificate(certFile, keyFile string) ClientOption {
+	return func(c *clientConfig) {
+		reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+		if _, err := reloader.load(); err != nil {
+			c.setErr(err)
+			return
+		}
+		if config := c.tlsConfig("WithClientCertificate"); config != nil {
+			config.GetClientCertificate = reloader.getClientCertificate
+		}
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version, such as tls.VersionTLS13.
+// The default is TLS 1.2.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(c *clientConfig) {
+		if config := c.tlsConfig("WithMinTLSVersion"); config != nil {
+			config.MinVersion = version
+		}
+	}
+}
+
+// WithCipherSuites restricts the cipher suites offered for TLS 1.0 to 1.2,
+// using the IDs defined in crypto/tls. TLS 1.3 suites are not configurable.
+func WithCipherSuites(ids ...uint16) ClientOption {
+	return func(c *clientConfig) {
+		if config := c.tlsConfig("WithCipherSuites"); config != nil {
+			config.CipherSuites = ids
+		}
+	}
+}
+
+// WithServerName overrides the server name sent in the TLS handshake (SNI)
+// and used to verify the server certificate.
+func WithServerName(name string) ClientOption {
+	return func(c *clientConfig) {
+		if config := c.tlsConfig("WithServerName"); config != nil {
+			config.ServerName = name
+		}
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the server certificate
+// chain and host name. It is meant for development only: any certificate is
+// accepted, which leaves connections open to interception. Public keys pinned
+// with WithPinnedPublicKeys are still checked, against the server's own
+// certificate only.
+func WithInsecureSkipVerify(skip bool) ClientOption {
+	return func(c *clientConfig) {
+		if config := c.tlsConfig("WithInsecureSkipVerify"); config != nil {
+			config.InsecureSkipVerify = skip
+		}
+	}
+}
+
+// WithPinnedPublicKeys requires at least one certificate of the server's
+// verified chain to carry one of the given public keys; when verification is
+// skipped, the server's own certificate must carry one. Pins are the base64-encoded
+// SHA-256 digest of the DER-encoded SubjectPublicKeyInfo, optionally
+// prefixed with "sha256/" as in curl's --pinnedpubkey. Handshakes with a
+// server that matches none of them fail with a *PinningError.
+func WithPinnedPublicKeys(pins ...string) ClientOption {
+	return func(c *clientConfig) {
+		pinned := make([]string, len(pins))
+		for i, pin := range pins {
+			pinned[i] = strings.TrimPrefix(pin, "sha256/")
+		}
+		config := c.tlsConfig("WithPinnedPublicKeys")
+		if config == nil {
+			return
+		}
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			var got []string
+			for _, cert := range pinnableCertificates(cs) {
+				pin := PublicKeyPin(cert)
+				if slices.Contains(pinned, pin) {
+					return nil
+				}
+				got = append(got, "sha256/"+pin)
+			}
+			return &PinningError{Host: cs.ServerName, Pins: got}
+		}
+	}
+}
+
+// pinnableCertificates returns the certificates whose keys may match a pin.
+// The chain sent by the server is not trusted as such: with verification on,
+// only the verified chains count; without it, only the leaf, whose key the
+// handshake proves the server holds.
+func pinnableCertificates(cs tls.ConnectionState) []*x509.Certificate {
+	if len(cs.VerifiedChains) == 0 {
+		if len(cs.PeerCertificates) == 0 {
+			return nil
+		}
+		return cs.PeerCertificates[:1]
+	}
+	var certs []*x509.Certificate
+	for _, chain := range cs.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	return certs
+}
+
+// PublicKeyPin returns the pin of cert's public key in the form expected by
+// WithPinnedPublicKeys, without the "sha256/" prefix.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// certReloader loads a client certificate and reloads it when its files change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// load returns the current certificate, reading the files again if either of
+// them was modified since the last load.
+func (r *certReloader) load() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			// Keep the previous certificate while the files are being replaced.
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("requesto: loading client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+// latestModTime returns the most recent modification time of the two files.
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("requesto: loading client certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate.
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.load()
+}
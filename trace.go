@@ -0,0 +1,141 @@
+package requesto
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TraceInfo holds the timing breakdown of a single request attempt.
+// It is only populated when tracing is enabled with WithTrace or
+// Request.SetTrace. Phases that did not happen, such as DNS resolution on a
+// reused connection, are zero.
+type TraceInfo struct {
+	// DNSLookup is the time spent resolving the host name.
+	DNSLookup time.Duration
+	// TCPConnect is the time spent establishing the TCP connection.
+	TCPConnect time.Duration
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+	// ConnectionTime is the time spent obtaining a connection, including
+	// DNS, TCP and TLS for new connections.
+	ConnectionTime time.Duration
+	// ServerTime is the time between writing the request and receiving the
+	// first response byte.
+	ServerTime time.Duration
+	// TimeToFirstByte is the time between starting the request and receiving
+	// the first response byte.
+	TimeToFirstByte time.Duration
+	// TotalTime is the time until the response was received. For buffered
+	// responses it includes reading the body.
+	TotalTime time.Duration
+
+	// ConnReused reports whether the connection was taken from the pool.
+	ConnReused bool
+	// ConnWasIdle reports whether a reused connection was idle in the pool.
+	ConnWasIdle bool
+	// ConnIdleTime is how long a reused connection had been idle.
+	ConnIdleTime time.Duration
+	// RemoteAddr is the address of the server the request was sent to.
+	RemoteAddr string
+}
+
+// requestTrace collects httptrace events for one attempt.
+type requestTrace struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	info TraceInfo
+}
+
+// clientTrace returns the httptrace hooks feeding the trace.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	now := func(dst *time.Time) {
+		t.mu.Lock()
+		*dst = time.Now()
+		t.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { now(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { now(&t.dnsDone) },
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			// Keep the first attempt when several addresses are dialed in parallel.
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				now(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { now(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { now(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = time.Now()
+			t.info.ConnReused = info.Reused
+			t.info.ConnWasIdle = info.WasIdle
+			t.info.ConnIdleTime = info.IdleTime
+			if info.Conn != nil {
+				t.info.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { now(&t.wroteRequest) },
+		GotFirstResponseByte: func() { now(&t.firstByte) },
+	}
+}
+
+// finish computes the TraceInfo once the attempt has completed.
+func (t *requestTrace) finish() TraceInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+
+	info := t.info
+	info.DNSLookup = since(t.dnsStart, t.dnsDone)
+	info.TCPConnect = since(t.connectStart, t.connectDone)
+	info.TLSHandshake = since(t.tlsStart, t.tlsDone)
+	info.ConnectionTime = since(t.start, t.gotConn)
+	info.ServerTime = since(t.wroteRequest, t.firstByte)
+	info.TimeToFirstByte = since(t.start, t.firstByte)
+	info.TotalTime = time.Since(t.start)
+	return info
+}
+
+// startTrace attaches timing hooks to req when tracing is enabled and returns
+// a function storing the result on the response.
+func (r *Request) startTrace(req *http.Request) (*http.Request, func(*Response)) {
+	if !r.trace {
+		return req, func(*Response) {}
+	}
+	t := &requestTrace{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t.clientTrace()))
+	return req, func(resp *Response) {
+		if resp != nil {
+			info := t.finish()
+			resp.traceInfo = &info
+		}
+	}
+}
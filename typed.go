@@ -0,0 +1,167 @@
+package requesto
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrUnexpectedContentType is returned by the typed JSON helpers when a
+// successful response does not declare a JSON Content-Type.
+var ErrUnexpectedContentType = errors.New("requesto: unexpected response Content-Type")
+
+// GetJSON sends a GET request to path and decodes the JSON response into T.
+//
+// Path is joined to the client's BaseURL, or replaces it if it is an absolute
+// URL. If client is nil, a one-off client is created and path is used as the
+// full URL. The optional arguments can be Headers, Params, AsHeaders or AsParams.
+//
+// Responses with a 4xx or 5xx status code are returned as an *HTTPError, whose
+// Result field holds the decoded error body if an error result type was
+// registered on the client.
+func GetJSON[T any](client *Client, path string, args ...any) (T, error) {
+	return sendJSON[T](client, http.MethodGet, path, nil, args)
+}
+
+// DeleteJSON sends a DELETE request to path and decodes the JSON response into T.
+// See GetJSON for the meaning of the arguments.
+func DeleteJSON[T any](client *Client, path string, args ...any) (T, error) {
+	return sendJSON[T](client, http.MethodDelete, path, nil, args)
+}
+
+// PostJSON sends body as JSON in a POST request to path and decodes the JSON
+// response into Resp. A nil body, including a nil pointer, map or slice, sends
+// no body. See GetJSON for the meaning of the other arguments.
+func PostJSON[Req, Resp any](client *Client, path string, body Req, args ...any) (Resp, error) {
+	return sendJSON[Resp](client, http.MethodPost, path, body, args)
+}
+
+// PutJSON sends body as JSON in a PUT request to path and decodes the JSON
+// response into Resp. See GetJSON for the meaning of the other arguments.
+func PutJSON[Req, Resp any](client *Client, path string, body Req, args ...any) (Resp, error) {
+	return sendJSON[Resp](client, http.MethodPut, path, body, args)
+}
+
+// PatchJSON sends body as JSON in a PATCH request to path and decodes the JSON
+// response into Resp. See GetJSON for the meaning of the other arguments.
+func PatchJSON[Req, Resp any](client *Client, path string, body Req, args ...any) (Resp, error) {
+	return sendJSON[Resp](client, http.MethodPatch, path, body, args)
+}
+
+// DoJSON sends a prepared request with the given method and decodes the JSON
+// response into T, applying the same status and Content-Type checks as GetJSON.
+func DoJSON[T any](req *Request, method string) (T, error) {
+	if req.headers.Get("Accept") == "" {
+		req.headers.Set("Accept", "application/json")
+	}
+	resp, err := req.Send(method)
+	return decodeJSON[T](resp, err)
+}
+
+// sendJSON builds and sends a request for the typed helpers.
+func sendJSON[T any](client *Client, method, path string, body any, args []any) (T, error) {
+	var req *Request
+	if client == nil {
+		req = NewClient(path).NewRequest()
+	} else {
+		req = client.NewRequest().JoinPath(path)
+	}
+
+	if err := applyRequestArgs(req, method, args); err != nil {
+		var zero T
+		return zero, err
+	}
+	if !isNilBody(body) {
+		req.SetJsonData(body)
+	}
+	return DoJSON[T](req, method)
+}
+
+// isNilBody reports whether body is nil or a nil pointer, map or slice, which
+// is sent as no body rather than as JSON null.
+func isNilBody(body any) bool {
+	if body == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(body); v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// applyRequestArgs applies Headers and Params style arguments to a request.
+func applyRequestArgs(req *Request, name string, args []any) error {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case Headers:
+			for key, value := range v {
+				req.headers.Set(key, value)
+			}
+		case requestHeaders:
+			for key, values := range v.data {
+				req.headers[key] = values
+			}
+		case Params:
+			req.params = mergeParams(req.params, v)
+		case queryParams:
+			req.params = mergeParams(req.params, v.data)
+		case nil:
+			continue
+		default:
+			return fmt.Errorf("unsupported argument type for %s: %T", name, v)
+		}
+	}
+	return nil
+}
+
+// mergeParams copies src into a fresh copy of dst so that caller maps are never modified.
+func mergeParams(dst, src map[string]string) map[string]string {
+	merged := make(map[string]string, len(dst)+len(src))
+	maps.Copy(merged, dst)
+	maps.Copy(merged, src)
+	return merged
+}
+
+// decodeJSON turns the outcome of a request into a typed result.
+func decodeJSON[T any](resp *Response, err error) (T, error) {
+	var zero T
+	if err != nil {
+		if resp != nil {
+			resp.Close()
+		}
+		return zero, err
+	}
+	if resp.IsError() {
+		resp.releaseErrorBody()
+		return zero, resp.RaiseForStatus()
+	}
+
+	body, err := resp.Bytes()
+	if err != nil {
+		return zero, err
+	}
+	if len(body) == 0 || resp.Resp.StatusCode == http.StatusNoContent {
+		return zero, nil
+	}
+
+	contentType := resp.Resp.Header.Get("Content-Type")
+	if !isJSONContentType(contentType) {
+		return zero, fmt.Errorf("%w: expected JSON, got %q", ErrUnexpectedContentType, contentType)
+	}
+	return ToStruct[T](resp)
+}
+
+// isJSONContentType reports whether contentType is application/json or a
+// structured syntax "+json" media type.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}